@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"effective_mobile/src/_core/config"
+	"effective_mobile/src/_core/db"
+	"effective_mobile/src/_core/logger"
+
+	"github.com/pressly/goose/v3"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	configFlag = &cli.StringFlag{
+		Name:  "config",
+		Usage: "path to the YAML config file (defaults to $" + config.FileEnv + ")",
+	}
+	dryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "print the SQL that would run without executing it",
+	}
+	dirFlag = &cli.StringFlag{
+		Name:  "dir",
+		Usage: "directory new migrations are written to",
+		Value: "migrations",
+	}
+)
+
+func main() {
+	app := &cli.App{
+		Name:      "migrate",
+		Usage:     "Manage the database schema",
+		UsageText: "go run ./cmd/migrate [--config FILE] [--dry-run] <command> [arguments]",
+		Flags:     []cli.Flag{configFlag, dryRunFlag},
+		Commands: []*cli.Command{
+			{
+				Name:   "status",
+				Usage:  "Show applied and pending migrations",
+				Action: withMigrator(status),
+			},
+			{
+				Name:   "version",
+				Usage:  "Print the current and latest schema versions",
+				Action: withMigrator(version),
+			},
+			{
+				Name:   "up",
+				Usage:  "Apply all pending migrations",
+				Action: withMigrator(up),
+			},
+			{
+				Name:      "up-to",
+				Usage:     "Apply pending migrations up to and including VERSION",
+				ArgsUsage: "VERSION",
+				Action:    withMigrator(upTo),
+			},
+			{
+				Name:   "down",
+				Usage:  "Roll back the most recent migration",
+				Action: withMigrator(down),
+			},
+			{
+				Name:      "down-to",
+				Usage:     "Roll back migrations until VERSION is the latest applied",
+				ArgsUsage: "VERSION",
+				Action:    withMigrator(downTo),
+			},
+			{
+				Name:   "redo",
+				Usage:  "Roll back the most recent migration and apply it again",
+				Action: withMigrator(redo),
+			},
+			{
+				Name:   "reset",
+				Usage:  "Roll back all migrations",
+				Action: withMigrator(reset),
+			},
+			{
+				Name:      "create",
+				Usage:     "Create a new migration file",
+				ArgsUsage: "NAME [sql|go]",
+				Flags:     []cli.Flag{dirFlag},
+				Action:    create,
+			},
+			{
+				Name:   "validate",
+				Usage:  "Check the embedded migrations without connecting to the database",
+				Action: validate,
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// step is a single migration run in the given direction.
+type step struct {
+	source *goose.Source
+	up     bool
+}
+
+// dbMigrator is a goose provider together with the dialect it runs on, so
+// dry runs print the SQL that dialect would execute.
+type dbMigrator struct {
+	*goose.Provider
+	dialect string
+}
+
+type command func(ctx context.Context, c *cli.Context, migrator dbMigrator) error
+
+// withMigrator loads the config, connects to the database and hands the
+// command a migrator over the shared pool.
+func withMigrator(run command) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		cfg, err := config.LoadFile(c.String("config"))
+		if err != nil {
+			return err
+		}
+
+		if _, err := logger.New(os.Stderr, cfg.Log.Level); err != nil {
+			return fmt.Errorf("failed to set up logger: %w", err)
+		}
+		if cfg.Storage == "memory" {
+			return errors.New("memory storage has no schema to migrate")
+		}
+
+		gormDB, err := db.Connect(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer db.Close(gormDB)
+
+		provider, err := db.NewMigrator(gormDB)
+		if err != nil {
+			return err
+		}
+
+		return run(ctx, c, dbMigrator{Provider: provider, dialect: gormDB.Dialector.Name()})
+	}
+}
+
+func status(ctx context.Context, c *cli.Context, migrator dbMigrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get migration status: %w", err)
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tSOURCE")
+	for _, st := range statuses {
+		appliedAt := "-"
+		if st.State == goose.StateApplied {
+			appliedAt = st.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", st.Source.Version, st.State, appliedAt, sourceName(st.Source))
+	}
+	return w.Flush()
+}
+
+func version(ctx context.Context, c *cli.Context, migrator dbMigrator) error {
+	current, latest, err := migrator.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get versions: %w", err)
+	}
+
+	fmt.Fprintf(c.App.Writer, "current: %d\nlatest:  %d\n", current, latest)
+	return nil
+}
+
+func up(ctx context.Context, c *cli.Context, migrator dbMigrator) error {
+	if c.Bool("dry-run") {
+		return dryRun(ctx, c, migrator, func(statuses []*goose.MigrationStatus) []step {
+			return pending(statuses, goose.MaxVersion)
+		})
+	}
+
+	results, err := migrator.Up(ctx)
+	return report(c, results, err)
+}
+
+func upTo(ctx context.Context, c *cli.Context, migrator dbMigrator) error {
+	target, err := versionArg(c)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("dry-run") {
+		return dryRun(ctx, c, migrator, func(statuses []*goose.MigrationStatus) []step {
+			return pending(statuses, target)
+		})
+	}
+
+	results, err := migrator.UpTo(ctx, target)
+	return report(c, results, err)
+}
+
+func down(ctx context.Context, c *cli.Context, migrator dbMigrator) error {
+	if c.Bool("dry-run") {
+		return dryRun(ctx, c, migrator, func(statuses []*goose.MigrationStatus) []step {
+			return latestApplied(statuses)
+		})
+	}
+
+	result, err := migrator.Down(ctx)
+	return report(c, []*goose.MigrationResult{result}, err)
+}
+
+func downTo(ctx context.Context, c *cli.Context, migrator dbMigrator) error {
+	target, err := versionArg(c)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("dry-run") {
+		return dryRun(ctx, c, migrator, func(statuses []*goose.MigrationStatus) []step {
+			return applied(statuses, target)
+		})
+	}
+
+	results, err := migrator.DownTo(ctx, target)
+	return report(c, results, err)
+}
+
+func redo(ctx context.Context, c *cli.Context, migrator dbMigrator) error {
+	if c.Bool("dry-run") {
+		return dryRun(ctx, c, migrator, func(statuses []*goose.MigrationStatus) []step {
+			steps := latestApplied(statuses)
+			if len(steps) == 1 {
+				steps = append(steps, step{source: steps[0].source, up: true})
+			}
+			return steps
+		})
+	}
+
+	result, err := migrator.Down(ctx)
+	if err != nil {
+		return report(c, nil, err)
+	}
+	reapplied, err := migrator.ApplyVersion(ctx, result.Source.Version, true)
+	return report(c, []*goose.MigrationResult{result, reapplied}, err)
+}
+
+func reset(ctx context.Context, c *cli.Context, migrator dbMigrator) error {
+	if c.Bool("dry-run") {
+		return dryRun(ctx, c, migrator, func(statuses []*goose.MigrationStatus) []step {
+			return applied(statuses, 0)
+		})
+	}
+
+	results, err := migrator.DownTo(ctx, 0)
+	return report(c, results, err)
+}
+
+func create(c *cli.Context) error {
+	name := c.Args().Get(0)
+	if name == "" {
+		return errors.New("usage: create NAME [sql|go]")
+	}
+
+	kind := c.Args().Get(1)
+	if kind == "" {
+		kind = "sql"
+	}
+	if kind != "sql" && kind != "go" {
+		return fmt.Errorf("invalid migration type: %s (allowed: sql/go)", kind)
+	}
+
+	goose.SetSequential(true)
+	if err := goose.Create(nil, c.String("dir"), name, kind); err != nil {
+		return fmt.Errorf("failed to create migration: %w", err)
+	}
+	return nil
+}
+
+func validate(c *cli.Context) error {
+	if err := db.ValidateMigrations(); err != nil {
+		return fmt.Errorf("invalid migrations:\n%w", err)
+	}
+
+	fmt.Fprintln(c.App.Writer, "migrations OK")
+	return nil
+}
+
+// dryRun prints the SQL of the steps plan selects from the current status
+// instead of running them.
+func dryRun(ctx context.Context, c *cli.Context, migrator dbMigrator, plan func([]*goose.MigrationStatus) []step) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get migration status: %w", err)
+	}
+
+	steps := plan(statuses)
+	if len(steps) == 0 {
+		fmt.Fprintln(c.App.Writer, "-- nothing to do")
+		return nil
+	}
+
+	for _, s := range steps {
+		direction := "down"
+		if s.up {
+			direction = "up"
+		}
+		fmt.Fprintf(c.App.Writer, "-- %s %s\n", direction, sourceName(s.source))
+
+		if s.source.Type != goose.TypeSQL {
+			fmt.Fprint(c.App.Writer, "-- Go migration, SQL is not available\n\n")
+			continue
+		}
+		query, err := db.MigrationSQL(migrator.dialect, s.source.Path, s.up)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.App.Writer, "%s\n\n", query)
+	}
+	return nil
+}
+
+// pending returns the pending migrations up to target, oldest first.
+func pending(statuses []*goose.MigrationStatus, target int64) []step {
+	var steps []step
+	for _, st := range statuses {
+		if st.State == goose.StatePending && st.Source.Version <= target {
+			steps = append(steps, step{source: st.Source, up: true})
+		}
+	}
+	return steps
+}
+
+// applied returns the applied migrations above target, newest first.
+func applied(statuses []*goose.MigrationStatus, target int64) []step {
+	var steps []step
+	for _, st := range slices.Backward(statuses) {
+		if st.State == goose.StateApplied && st.Source.Version > target {
+			steps = append(steps, step{source: st.Source, up: false})
+		}
+	}
+	return steps
+}
+
+func latestApplied(statuses []*goose.MigrationStatus) []step {
+	steps := applied(statuses, 0)
+	if len(steps) > 1 {
+		steps = steps[:1]
+	}
+	return steps
+}
+
+func report(c *cli.Context, results []*goose.MigrationResult, err error) error {
+	if errors.Is(err, goose.ErrNoNextVersion) {
+		fmt.Fprintln(c.App.Writer, "nothing to do")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(c.App.Writer, "nothing to do")
+	}
+	for _, res := range results {
+		fmt.Fprintf(c.App.Writer, "%-4s %s (%s)\n", res.Direction, sourceName(res.Source), res.Duration.Round(time.Millisecond))
+	}
+	return nil
+}
+
+func versionArg(c *cli.Context) (int64, error) {
+	arg := c.Args().First()
+	if arg == "" {
+		return 0, errors.New("missing VERSION argument")
+	}
+
+	version, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid version: %s", arg)
+	}
+	return version, nil
+}
+
+func sourceName(source *goose.Source) string {
+	if source.Path == "" {
+		return strconv.FormatInt(source.Version, 10)
+	}
+	return source.Path
+}
@@ -22,6 +22,123 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the tenant's audit log, newest first. Requires the admin token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "List audit events",
+                "parameters": [
+                    {
+                        "enum": [
+                            "create",
+                            "update",
+                            "delete",
+                            "restore"
+                        ],
+                        "type": "string",
+                        "description": "Action to filter by",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 100,
+                        "type": "string",
+                        "description": "Actor to filter by",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 100,
+                        "type": "string",
+                        "description": "Claimed actor (X-Actor header) to filter by",
+                        "name": "actor_claimed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entity ID to filter by",
+                        "name": "entity_id",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 50,
+                        "type": "string",
+                        "description": "Entity type to filter by, e.g. subscription",
+                        "name": "entity_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events at or after this time (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum number of results to return (1-500, default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Offset for pagination",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subscription ID to filter by, including its scheduled changes, discounts and members",
+                        "name": "subscription_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events before this time (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/audit.ResAuditEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/subscriptions": {
             "get": {
                 "description": "Returns a list of subscriptions with optional filtering",
@@ -35,21 +152,46 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Filter by user ID",
-                        "name": "user_id",
+                        "description": "List the subscriptions as they were at this time (RFC 3339)",
+                        "name": "as_of",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Limit the number of results",
+                        "type": "string",
+                        "description": "Maximum number of results to return",
                         "name": "limit",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
+                        "type": "string",
                         "description": "Offset for pagination",
                         "name": "offset",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "trial",
+                            "active",
+                            "paused",
+                            "cancelled",
+                            "expired"
+                        ],
+                        "type": "string",
+                        "description": "Lifecycle status to filter by",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only trials ending this month or the next",
+                        "name": "trial_ending_soon",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID to filter by",
+                        "name": "user_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -65,13 +207,13 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
@@ -109,13 +251,19 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
@@ -123,7 +271,7 @@ const docTemplate = `{
         },
         "/subscriptions/summary": {
             "get": {
-                "description": "Calculate total cost of subscriptions for selected period with optional filters",
+                "description": "Calculate total cost of subscriptions for selected period with optional filters. Every active month is billed at the price in effect in that month, including prices scheduled for future months, less its discounts. Reports gross, discount and net totals. With user_id, shared subscriptions count for the user's share, while paid_price keeps the full cost of the subscriptions the user owns.",
                 "produces": [
                     "application/json"
                 ],
@@ -132,6 +280,12 @@ const docTemplate = `{
                 ],
                 "summary": "Get subscription summary",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Compute over the data as it was at this time (RFC 3339)",
+                        "name": "as_of",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "End of the period (MM-YYYY format)",
@@ -154,6 +308,83 @@ const docTemplate = `{
                         "in": "query",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "User ID to filter by; shared subscriptions count for the user's share",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ResSubscriptionSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/trash": {
+            "get": {
+                "description": "Returns subscriptions in the trash, most recently deleted first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "List deleted subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "List the subscriptions as they were at this time (RFC 3339)",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum number of results to return",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Offset for pagination",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "trial",
+                            "active",
+                            "paused",
+                            "cancelled",
+                            "expired"
+                        ],
+                        "type": "string",
+                        "description": "Lifecycle status to filter by",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only trials ending this month or the next",
+                        "name": "trial_ending_soon",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "User ID to filter by",
@@ -165,19 +396,22 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.SubscriptionSummaryResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscriptions.ResSubscription"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
@@ -200,6 +434,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Return the subscription as it was at this time (RFC 3339)",
+                        "name": "as_of",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -212,19 +452,19 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
@@ -269,25 +509,31 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Deletes a subscription by ID",
+                "description": "Moves a subscription to the trash; it can be restored until purged",
                 "tags": [
                     "Subscriptions"
                 ],
@@ -308,127 +554,1431 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/subscriptions.ErrorResponse"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "subscriptions.CreateSubscription": {
-            "type": "object",
-            "required": [
-                "end_date",
-                "price",
-                "service_name",
-                "start_date",
-                "user_id"
-            ],
-            "properties": {
-                "end_date": {
-                    "description": "Date when the subscription end (MM-YYYY format)\nrequired: true\nexample: \"12-2025\"",
-                    "type": "string"
-                },
-                "price": {
-                    "description": "Monthly subscription cost in RUB\nrequired: true\nexample: 599.99\nminimum: 0",
-                    "type": "number"
-                },
-                "service_name": {
-                    "description": "Name of the service being subscribed to\nrequired: true\nexample: Netflix\nmin length: 2\nmax length: 100",
-                    "type": "string",
-                    "maxLength": 100,
-                    "minLength": 2
-                },
-                "start_date": {
-                    "description": "Date when the subscription start (MM-YYYY format)\nrequired: true\nexample: \"07-2025\"",
-                    "type": "string"
-                },
-                "user_id": {
-                    "description": "Unique identifier of the user who owns the subscription\nrequired: true\nexample: 4093b3c9-5a25-42d9-8b98-13bba54c9e61\nformat: uuid",
-                    "type": "string"
-                }
-            }
         },
-        "subscriptions.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "errors": {
-                    "description": "Optional list of detailed errors\nexample: [\"invalid user ID format\", \"price must be positive\"]",
-                    "type": "array",
-                    "items": {
-                        "type": "string"
+        "/subscriptions/{id}/activate": {
+            "post": {
+                "description": "Makes a trial subscription active; it is billed from the current month",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "End a trial",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ResSubscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
                     }
-                },
-                "message": {
-                    "description": "Error message\nexample: invalid request payload",
-                    "type": "string"
-                },
-                "status_code": {
-                    "description": "HTTP status code\nexample: 400",
-                    "type": "integer"
                 }
             }
         },
-        "subscriptions.ResSubscription": {
+        "/subscriptions/{id}/cancel": {
+            "post": {
+                "description": "Cancels a subscription from the current month, or at the end of it with at_period_end",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Cancel a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Cancellation",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.CancelSubscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ResSubscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/changes": {
+            "get": {
+                "description": "Returns every change scheduled for a subscription, including applied and cancelled ones",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "List scheduled changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscriptions.ResScheduledChange"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Schedules a new price and/or service name from a future month. A change already pending for that month is replaced.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Schedule a price or plan change",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Scheduled change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ScheduleChange"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ResScheduledChange"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/changes/{changeId}": {
+            "delete": {
+                "description": "Cancels a change that has not been applied yet",
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Cancel a scheduled change",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Change ID",
+                        "name": "changeId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/discounts": {
+            "get": {
+                "description": "Returns the current discounts of a subscription in the order they were added",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "List discounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscriptions.ResDiscount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Attaches a percentage or fixed discount to a subscription, over a month range or a number of billed months",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Add a discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Discount",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.AddDiscount"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ResDiscount"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/discounts/{discountId}": {
+            "delete": {
+                "description": "Removes a discount from a subscription; summaries as of an earlier time still apply it",
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Remove a discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Discount ID",
+                        "name": "discountId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/history": {
+            "get": {
+                "description": "Returns every recorded change of a subscription and of its scheduled changes, discounts and members, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "Get subscription history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "create",
+                            "update",
+                            "delete",
+                            "restore"
+                        ],
+                        "type": "string",
+                        "description": "Action to filter by",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 100,
+                        "type": "string",
+                        "description": "Actor to filter by",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 100,
+                        "type": "string",
+                        "description": "Claimed actor (X-Actor header) to filter by",
+                        "name": "actor_claimed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entity ID to filter by",
+                        "name": "entity_id",
+                        "in": "query"
+                    },
+                    {
+                        "maxLength": 50,
+                        "type": "string",
+                        "description": "Entity type to filter by, e.g. subscription",
+                        "name": "entity_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events at or after this time (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum number of results to return (1-500, default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Offset for pagination",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subscription ID to filter by, including its scheduled changes, discounts and members",
+                        "name": "subscription_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events before this time (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/audit.ResAuditEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/members": {
+            "get": {
+                "description": "Returns the users sharing a subscription besides its owner",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "List members",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscriptions.ResMember"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds a user sharing the subscription, with an equal, percent or fixed share of its monthly cost",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Share a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Member",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.AddMember"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ResMember"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/members/{userId}": {
+            "put": {
+                "description": "Changes how the share of a user sharing the subscription is computed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Change a member's share",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Member user ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Share",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.UpdateMember"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ResMember"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes a user sharing the subscription; summaries as of an earlier time still count their share",
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Stop sharing a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Member user ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/pause": {
+            "post": {
+                "description": "Stops billing an active subscription from the current month",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Pause a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ResSubscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/restore": {
+            "post": {
+                "description": "Moves a subscription out of the trash",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Restore subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ResSubscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/resume": {
+            "post": {
+                "description": "Bills a paused subscription again from the current month",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Resume a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/subscriptions.ResSubscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "audit.Change": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "Value before the change, null when the entity did not exist"
+                },
+                "to": {
+                    "description": "Value after the change, null when the entity was deleted"
+                }
+            }
+        },
+        "audit.ResAuditEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "create, update, delete or restore",
+                    "type": "string"
+                },
+                "actor": {
+                    "description": "Who made the change, as verified by the service (anonymous when unknown)",
+                    "type": "string"
+                },
+                "actor_claimed": {
+                    "description": "Who the request said made the change (X-Actor header, not verified)",
+                    "type": "string"
+                },
+                "changes": {
+                    "description": "Changed fields with their old and new values",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/audit.Change"
+                    }
+                },
+                "created_at": {
+                    "description": "When the change was made",
+                    "type": "string"
+                },
+                "entity_id": {
+                    "description": "Identifier of the entity that changed",
+                    "type": "string"
+                },
+                "entity_type": {
+                    "description": "Kind of entity that changed, e.g. subscription",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the event",
+                    "type": "string"
+                },
+                "request_id": {
+                    "description": "Request that made the change",
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "description": "Subscription the entity is or belongs to",
+                    "type": "string"
+                }
+            }
+        },
+        "response.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "description": "Optional list of detailed errors",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "message": {
+                    "description": "Error message",
+                    "type": "string"
+                },
+                "status_code": {
+                    "description": "HTTP status code",
+                    "type": "integer"
+                },
+                "trace_id": {
+                    "description": "ID of the request trace, to look the failure up in the tracing backend",
+                    "type": "string"
+                }
+            }
+        },
+        "subscriptions.AddDiscount": {
+            "type": "object",
+            "required": [
+                "amount",
+                "kind",
+                "start_date"
+            ],
+            "properties": {
+                "amount": {
+                    "description": "Percentage (up to 100) or amount in RUB",
+                    "type": "number"
+                },
+                "cycles": {
+                    "description": "Number of billed months discounted from start_date; without it or\nend_date the discount lasts as long as the subscription",
+                    "type": "integer"
+                },
+                "description": {
+                    "description": "What the discount is for, e.g. a promo code",
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "end_date": {
+                    "description": "Last discounted month (MM-YYYY format)",
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "percent of the monthly price, or a fixed amount off it",
+                    "type": "string",
+                    "enum": [
+                        "percent",
+                        "fixed"
+                    ]
+                },
+                "start_date": {
+                    "description": "First discounted month (MM-YYYY format)",
+                    "type": "string"
+                }
+            }
+        },
+        "subscriptions.AddMember": {
+            "type": "object",
+            "required": [
+                "split",
+                "user_id"
+            ],
+            "properties": {
+                "share": {
+                    "description": "Percentage (up to 100) or monthly amount in RUB; not used with equal",
+                    "type": "number"
+                },
+                "split": {
+                    "description": "How the user's share is computed: equal, percent or fixed",
+                    "type": "string",
+                    "enum": [
+                        "equal",
+                        "percent",
+                        "fixed"
+                    ]
+                },
+                "user_id": {
+                    "description": "User sharing the subscription",
+                    "type": "string"
+                }
+            }
+        },
+        "subscriptions.CancelSubscription": {
+            "type": "object",
+            "properties": {
+                "at_period_end": {
+                    "description": "Keep the subscription until the end of the current month instead of\ncancelling it right away",
+                    "type": "boolean"
+                },
+                "reason": {
+                    "description": "Why the subscription is cancelled",
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "subscriptions.CreateSubscription": {
+            "type": "object",
+            "required": [
+                "end_date",
+                "price",
+                "service_name",
+                "start_date",
+                "user_id"
+            ],
+            "properties": {
+                "cancel_after_trial": {
+                    "description": "Cancel the subscription when the trial ends instead of converting it\nto paid",
+                    "type": "boolean"
+                },
+                "end_date": {
+                    "description": "Date when the subscription end (MM-YYYY format)",
+                    "type": "string"
+                },
+                "price": {
+                    "description": "Price",
+                    "type": "number"
+                },
+                "service_name": {
+                    "description": "Name of the service being subscribed to",
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 2
+                },
+                "start_date": {
+                    "description": "Date when the subscription start (MM-YYYY format)",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Initial status, trial or active (default active, or trial with a\ntrial_end)",
+                    "type": "string",
+                    "enum": [
+                        "trial",
+                        "active"
+                    ]
+                },
+                "trial_end": {
+                    "description": "Last month of the trial (MM-YYYY format, required with status trial)",
+                    "type": "string"
+                },
+                "trial_price": {
+                    "description": "Monthly cost during the trial (default free)",
+                    "type": "number",
+                    "minimum": 0
+                },
+                "trial_start": {
+                    "description": "First month of the trial (MM-YYYY format, default start_date)",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "Unique identifier of the user who owns the subscription",
+                    "type": "string"
+                }
+            }
+        },
+        "subscriptions.ResDiscount": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Percentage or amount in RUB",
+                    "type": "number"
+                },
+                "created_at": {
+                    "description": "When the discount was added",
+                    "type": "string"
+                },
+                "cycles": {
+                    "description": "Number of billed months discounted",
+                    "type": "integer"
+                },
+                "description": {
+                    "description": "What the discount is for",
+                    "type": "string"
+                },
+                "end_date": {
+                    "description": "Last discounted month (MM-YYYY format)",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the discount",
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "percent or fixed",
+                    "type": "string"
+                },
+                "start_date": {
+                    "description": "First discounted month (MM-YYYY format)",
+                    "type": "string"
+                }
+            }
+        },
+        "subscriptions.ResMember": {
+            "type": "object",
+            "properties": {
+                "share": {
+                    "description": "Percentage or monthly amount in RUB",
+                    "type": "number"
+                },
+                "split": {
+                    "description": "equal, percent or fixed",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "When the member was added or last changed",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "User sharing the subscription",
+                    "type": "string"
+                }
+            }
+        },
+        "subscriptions.ResScheduledChange": {
+            "type": "object",
+            "properties": {
+                "applied_at": {
+                    "description": "When the change took effect",
+                    "type": "string"
+                },
+                "cancelled_at": {
+                    "description": "When the change was cancelled or replaced",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "When the change was scheduled",
+                    "type": "string"
+                },
+                "effective_from": {
+                    "description": "Month the change takes effect (MM-YYYY format)",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the change",
+                    "type": "string"
+                },
+                "price": {
+                    "description": "New monthly cost, unset when the price does not change",
+                    "type": "number"
+                },
+                "service_name": {
+                    "description": "New service name, unset when the plan does not change",
+                    "type": "string"
+                }
+            }
+        },
+        "subscriptions.ResSubscription": {
             "type": "object",
             "properties": {
+                "cancel_at": {
+                    "description": "Month the subscription is cancelled from (MM-YYYY format); set ahead\nof time for cancellations at the end of the current month",
+                    "type": "string"
+                },
+                "cancellation_reason": {
+                    "description": "Reason given for the cancellation",
+                    "type": "string"
+                },
+                "cancelled_at": {
+                    "description": "When the subscription was cancelled",
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "When the subscription was moved to the trash, only set for trashed ones",
+                    "type": "string"
+                },
                 "end_date": {
-                    "description": "Optional subscription end date (MM-YYYY format)\nexample: \"12-2025\"",
+                    "description": "Optional subscription end date (MM-YYYY format)",
                     "type": "string"
                 },
                 "id": {
-                    "description": "Unique identifier of the subscription\nexample: b2c3d4e5-6789-01fg-hijk-lmnopqrstuvw\nformat: uuid",
+                    "description": "Unique identifier of the subscription",
                     "type": "string"
                 },
+                "pending_changes": {
+                    "description": "Price and plan changes scheduled for future months",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscriptions.ResScheduledChange"
+                    }
+                },
                 "price": {
-                    "description": "Monthly subscription cost in RUB\nexample: 599.99",
+                    "description": "Monthly subscription cost in RUB",
                     "type": "number"
                 },
                 "service_name": {
-                    "description": "Name of the subscribed service\nexample: Netflix",
+                    "description": "Name of the subscribed service",
                     "type": "string"
                 },
                 "start_date": {
-                    "description": "Subscription start date (MM-YYYY format)\nexample: \"07-2025\"",
+                    "description": "Subscription start date (MM-YYYY format)",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Lifecycle status: trial, active, paused, cancelled or expired",
+                    "type": "string"
+                },
+                "trial_end": {
+                    "description": "Last month of the trial (MM-YYYY format, required with status trial)",
+                    "type": "string"
+                },
+                "trial_price": {
+                    "description": "Monthly cost during the trial, unset when free",
+                    "type": "number"
+                },
+                "trial_start": {
+                    "description": "First month of the trial (MM-YYYY format)",
                     "type": "string"
                 },
                 "user_id": {
-                    "description": "Unique identifier of the user\nexample: a1b2c3d4-5678-90ef-ghij-klmnopqrstuv\nformat: uuid",
+                    "description": "Unique identifier of the user",
                     "type": "string"
                 }
             }
         },
-        "subscriptions.SubscriptionSummaryResponse": {
+        "subscriptions.ResSubscriptionSummary": {
             "type": "object",
             "properties": {
                 "count": {
-                    "description": "Number of subscriptions matched\nexample: 6",
+                    "description": "Number of subscriptions matched",
                     "type": "integer"
                 },
+                "discount": {
+                    "description": "Total of the discounts applied",
+                    "type": "number"
+                },
                 "end_date": {
-                    "description": "Period end (MM-YYYY format)\nexample: \"12-2025\"",
+                    "description": "Period end (MM-YYYY format)",
                     "type": "string"
                 },
+                "gross_price": {
+                    "description": "Cost before discounts, each month at the price in effect then",
+                    "type": "number"
+                },
+                "net_price": {
+                    "description": "Cost after discounts",
+                    "type": "number"
+                },
+                "paid_price": {
+                    "description": "What is paid: with user_id, the full net cost of the subscriptions\nthe user owns, including the shares of their members",
+                    "type": "number"
+                },
                 "start_date": {
-                    "description": "Period start (MM-YYYY format)\nexample: \"07-2025\"",
+                    "description": "Period start (MM-YYYY format)",
                     "type": "string"
                 },
                 "total_price": {
-                    "description": "Total cost for the period\nexample: 3599.94",
+                    "description": "Total cost for the period after discounts, same as net_price",
+                    "type": "number"
+                }
+            }
+        },
+        "subscriptions.ScheduleChange": {
+            "type": "object",
+            "required": [
+                "effective_from"
+            ],
+            "properties": {
+                "effective_from": {
+                    "description": "Month the change takes effect, after the current one (MM-YYYY format)",
+                    "type": "string"
+                },
+                "price": {
+                    "description": "New monthly cost",
+                    "type": "number"
+                },
+                "service_name": {
+                    "description": "New service name or plan",
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 2
+                }
+            }
+        },
+        "subscriptions.UpdateMember": {
+            "type": "object",
+            "required": [
+                "split"
+            ],
+            "properties": {
+                "share": {
+                    "description": "Percentage (up to 100) or monthly amount in RUB; not used with equal",
                     "type": "number"
+                },
+                "split": {
+                    "description": "How the user's share is computed: equal, percent or fixed",
+                    "type": "string",
+                    "enum": [
+                        "equal",
+                        "percent",
+                        "fixed"
+                    ]
                 }
             }
         },
@@ -436,25 +1986,36 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "end_date": {
-                    "description": "New end date (MM-YYYY format)\nexample: \"12-2025\"",
+                    "description": "New end date (MM-YYYY format)",
                     "type": "string"
                 },
                 "price": {
-                    "description": "New monthly cost\nexample: 799.99",
+                    "description": "New monthly cost",
                     "type": "number"
                 },
+                "price_effective_from": {
+                    "description": "Month the new price applies from (MM-YYYY format); earlier months keep\ntheir price. Without it the price is corrected for the whole period.",
+                    "type": "string"
+                },
                 "service_name": {
-                    "description": "New service name\nexample: Netflix Premium",
+                    "description": "New service name",
                     "type": "string",
                     "maxLength": 100,
                     "minLength": 2
                 },
                 "start_date": {
-                    "description": "New start date (MM-YYYY format)\nexample: \"07-2025\"",
+                    "description": "New start date (MM-YYYY format)",
                     "type": "string"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -1,32 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	_ "effective_mobile/docs"
+	"effective_mobile/src/_core/actor"
 	"effective_mobile/src/_core/config"
 	"effective_mobile/src/_core/db"
+	"effective_mobile/src/_core/health"
+	"effective_mobile/src/_core/logger"
+	"effective_mobile/src/_core/metrics"
+	"effective_mobile/src/_core/middleware"
+	"effective_mobile/src/_core/tenant"
+	"effective_mobile/src/_core/tracing"
+	"effective_mobile/src/_core/worker"
+	"effective_mobile/src/audit"
 	"effective_mobile/src/subscriptions"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"github.com/urfave/cli/v2"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"gorm.io/gorm"
 )
 
-func enableCORS(router *mux.Router) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == "OPTIONS" {
-			return
-		}
-
-		router.ServeHTTP(w, r)
-	})
+var configFlag = &cli.StringFlag{
+	Name:  "config",
+	Usage: "path to the YAML config file (defaults to $" + config.FileEnv + ")",
 }
 
 // @title Subscription Service API
@@ -42,30 +51,162 @@ func enableCORS(router *mux.Router) http.Handler {
 // @host localhost:4000
 // @BasePath /api
 // @schemes http
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
-	cfg, err := config.Load()
+	app := &cli.App{
+		Name:   "subscriptions",
+		Usage:  "Subscription Service API",
+		Flags:  []cli.Flag{configFlag},
+		Action: serve,
+		Commands: []*cli.Command{
+			{
+				Name:  "config",
+				Usage: "Inspect the configuration",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "print",
+						Usage:  "Print the effective configuration with secrets redacted",
+						Action: printConfig,
+					},
+				},
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func serve(c *cli.Context) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.LoadFile(c.String("config"))
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		return err
 	}
 
-	gormDB, err := db.Connect()
+	if _, err := logger.New(os.Stdout, cfg.Log.Level); err != nil {
+		return fmt.Errorf("failed to set up logger: %w", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Enabled:     cfg.Tracing.Enabled,
+		ServiceName: cfg.Tracing.ServiceName,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		FilePath:    cfg.Tracing.FilePath,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	var defaultTenant *uuid.UUID
+	if cfg.Tenant.Default != "" {
+		id, err := uuid.Parse(cfg.Tenant.Default)
+		if err != nil {
+			return fmt.Errorf("invalid default tenant: %w", err)
+		}
+		defaultTenant = &id
+	}
+
+	state := health.New(health.Options{
+		ReadyTimeout:  cfg.Health.ReadyTimeout,
+		ReportTimeout: cfg.Health.ReportTimeout,
+	})
+
+	var (
+		cluster          *db.Cluster
+		subscriptionRepo subscriptions.SubscriptionRepository
+		auditRepo        audit.AuditRepository
+	)
+	switch cfg.Storage {
+	case "memory":
+		slog.Warn("Using in-memory storage, data is lost on restart")
+		subscriptionRepo = subscriptions.NewMemorySubscriptionRepo()
+		auditRepo = audit.NewMemoryAuditRepo()
+	default:
+		cluster, err = openDatabase(ctx, cfg, state)
+		if err != nil {
+			return err
+		}
+		subscriptionRepo = subscriptions.NewSubscriptionRepo(cluster)
+		auditRepo = audit.NewAuditRepo(cluster)
 	}
 
-	if err := db.RunMigrations("up"); err != nil {
-		log.Fatal("Migration failed: ", err)
+	workers := worker.NewGroup()
+	if cluster != nil && len(cluster.Replicas()) > 0 {
+		workers.Every("replica-health", cfg.DB.ReplicaCheckInterval, cluster.CheckReplicas)
 	}
 
-	subscriptionRepo := subscriptions.NewSubscriptionRepo(gormDB)
-	subscriptionService := subscriptions.NewSubscriptionService(subscriptionRepo)
+	auditService := audit.NewAuditService(auditRepo)
+	auditController := audit.NewAuditController(auditService)
+
+	subscriptionService := subscriptions.NewSubscriptionService(subscriptionRepo, auditService)
 	subscriptionController := subscriptions.NewSubscriptionController(subscriptionService)
 
+	if cfg.Trash.Retention > 0 {
+		workers.Every("trash-purge", cfg.Trash.PurgeInterval, func(ctx context.Context) error {
+			return subscriptionService.PurgeDeleted(ctx, cfg.Trash.Retention)
+		})
+	}
+	workers.Every("scheduled-changes", cfg.Scheduler.Interval, subscriptionService.ApplyDueChanges)
+	workers.Every("lifecycle", cfg.Scheduler.Interval, subscriptionService.AdvanceLifecycle)
+	if cfg.Metrics.Enabled {
+		workers.Every("business-metrics", cfg.Metrics.RefreshInterval, subscriptionService.RefreshMetrics)
+	}
+
 	// ROUTERS
 	r := mux.NewRouter()
-	corsRouter := enableCORS(r)
+	corsRouter := middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   cfg.CORS.ExposedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
+	}, r)(r)
+
+	// Health probes are polled every few seconds; they are served on their
+	// own, outside the access logs and HTTP metrics.
+	probes := mux.NewRouter()
+	probes.Use(middleware.Recover)
+	state.RegisterRoutes(probes)
+
+	// MIDDLEWARE (outermost first)
+	// Request IDs, metrics and access logs wrap the whole handler so
+	// requests no route serves, like 404s, 405s and CORS preflights, are
+	// counted and logged too.
+	handler := middleware.AccessLog(corsRouter)
+	if cfg.Metrics.Enabled {
+		handler = middleware.Metrics(handler)
+	}
+	handler = middleware.RequestID(middleware.Route(r)(handler))
+	handler = middleware.Bypass(probes, handler)
+	if cfg.Tracing.Enabled {
+		r.Use(otelmux.Middleware(cfg.Tracing.ServiceName))
+		r.Use(middleware.TraceLog)
+	}
+	r.Use(middleware.Recover)
+	r.Use(middleware.Timeout(cfg.API.RequestTimeout, cfg.API.RouteTimeouts))
+	r.Use(middleware.MaxBytes(cfg.API.MaxBodyBytes))
+
+	if cfg.Metrics.Enabled {
+		r.Handle(cfg.Metrics.Path, metrics.Handler()).Methods("GET")
+	}
 	api := r.PathPrefix("/api").Subrouter()
+	api.Use(tenant.Middleware(defaultTenant, cfg.Tenant.TrustHeader))
+	api.Use(middleware.ReadConsistency)
+	api.Use(actor.Middleware)
 	subscriptionController.RegisterRoutes(api)
+	auditController.RegisterRoutes(api, actor.AdminOnly(cfg.Audit.AdminToken))
 
 	r.PathPrefix("/swagger/").Handler(httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"),
@@ -73,14 +214,177 @@ func main() {
 	))
 
 	srv := &http.Server{
-		Handler:      corsRouter,
-		Addr:         fmt.Sprintf(":%d", cfg.API.Port),
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
+		Handler:           handler,
+		Addr:              fmt.Sprintf(":%d", cfg.API.Port),
+		ReadTimeout:       cfg.API.ReadTimeout,
+		ReadHeaderTimeout: cfg.API.ReadHeaderTimeout,
+		WriteTimeout:      cfg.API.WriteTimeout,
+		IdleTimeout:       cfg.API.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", slog.String("addr", srv.Addr))
+		serverErr <- srv.ListenAndServe()
+	}()
+	state.SetReady(true)
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server failed: %w", err)
+		}
+	case <-ctx.Done():
 	}
+	stop()
 
-	log.Printf("Starting server on %s", srv.Addr)
-	if err := srv.ListenAndServe(); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	slog.Info("Shutting down")
+	state.SetReady(false)
+	time.Sleep(cfg.Shutdown.PreStopDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to drain HTTP connections", slog.Any("error", err))
+	}
+	if err := workers.Stop(shutdownCtx); err != nil {
+		slog.Error("Failed to stop background workers", slog.Any("error", err))
 	}
+	if cluster != nil {
+		if err := cluster.Close(); err != nil {
+			slog.Error("Failed to close replicas", slog.Any("error", err))
+		}
+		if err := db.Close(cluster.Primary()); err != nil {
+			slog.Error("Failed to close database", slog.Any("error", err))
+		}
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", slog.Any("error", err))
+	}
+
+	slog.Info("Server stopped")
+	return nil
+}
+
+// openDatabase connects to Postgres or SQLite and the read replicas, applies
+// the migration policy and registers the database plugins and health checks.
+func openDatabase(ctx context.Context, cfg *config.Config, state *health.Health) (*db.Cluster, error) {
+	gormDB, err := db.Connect(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
+	}
+
+	if err := migrate(ctx, cfg, gormDB); err != nil {
+		return nil, err
+	}
+
+	cluster, err := db.NewCluster(gormDB, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replicas: %w", err)
+	}
+
+	system := "postgresql"
+	if cfg.Storage == db.SQLite {
+		system = "sqlite"
+	}
+	for i, handle := range append([]*gorm.DB{gormDB}, cluster.Replicas()...) {
+		if cfg.Tracing.Enabled {
+			if err := handle.Use(tracing.GormPlugin{System: system}); err != nil {
+				return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+			}
+		}
+		if cfg.Metrics.Enabled {
+			if err := handle.Use(metrics.GormPlugin{}); err != nil {
+				return nil, fmt.Errorf("failed to register metrics plugin: %w", err)
+			}
+			handleDB, err := handle.DB()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get database handle: %w", err)
+			}
+			name := db.Name(cfg)
+			if i > 0 {
+				name = fmt.Sprintf("%s-replica-%d", name, i-1)
+			}
+			metrics.RegisterDBStats(handleDB, name)
+		}
+	}
+
+	state.AddCheck("database", cfg.Health.DBTimeout, func(ctx context.Context) (any, error) {
+		return nil, sqlDB.PingContext(ctx)
+	})
+	state.AddCheck("migrations", cfg.Health.MigrationsTimeout, func(ctx context.Context) (any, error) {
+		current, expected, err := db.MigrationVersions(ctx, gormDB)
+		details := map[string]any{"current": current, "expected": expected, "policy": cfg.Migrations.Policy}
+		if err == nil && current != expected && cfg.Migrations.Policy != "skip" {
+			err = fmt.Errorf("schema version %d, expected %d", current, expected)
+		}
+		return details, err
+	})
+	if len(cluster.Replicas()) > 0 {
+		// Reads fall back to the primary, so replicas never fail readiness.
+		state.AddCheck("replicas", cfg.Health.DBTimeout, func(context.Context) (any, error) {
+			return cluster.ReplicaStatus(), nil
+		})
+	}
+
+	return cluster, nil
+}
+
+// migrate applies the startup migration policy and logs the schema version
+// the service runs against.
+func migrate(ctx context.Context, cfg *config.Config, gormDB *gorm.DB) error {
+	policy := cfg.Migrations.Policy
+	if policy == "auto" {
+		if err := db.RunMigrations(ctx, gormDB, "up"); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+	}
+
+	current, expected, err := db.MigrationVersions(ctx, gormDB)
+	if err != nil {
+		if policy == "skip" {
+			slog.Warn("Failed to read schema version", slog.String("policy", policy), slog.Any("error", err))
+			return nil
+		}
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	attrs := []any{
+		slog.String("policy", policy),
+		slog.Int64("current", current),
+		slog.Int64("expected", expected),
+	}
+	switch {
+	case current == expected:
+		slog.Info("Schema is up to date", attrs...)
+	case policy == "verify" && cfg.Migrations.OnMismatch == "fail":
+		return fmt.Errorf("schema version %d, expected %d: apply migrations before starting", current, expected)
+	case policy == "verify":
+		slog.Warn("Schema version mismatch, starting not ready", attrs...)
+	default:
+		slog.Warn("Schema version mismatch", attrs...)
+	}
+
+	return nil
+}
+
+func printConfig(c *cli.Context) error {
+	cfg, err := config.LoadFile(c.String("config"))
+	if err != nil {
+		return err
+	}
+
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.App.Writer.Write(out)
+	return err
 }
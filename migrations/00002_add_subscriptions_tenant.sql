@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE subscriptions ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+
+CREATE INDEX idx_subscriptions_tenant_user ON subscriptions(tenant_id, user_id);
+CREATE INDEX idx_subscriptions_tenant_service ON subscriptions(tenant_id, service_name);
+CREATE INDEX idx_subscriptions_tenant_period ON subscriptions(tenant_id, start_date, end_date);
+
+-- +goose Down
+DROP INDEX idx_subscriptions_tenant_period;
+DROP INDEX idx_subscriptions_tenant_service;
+DROP INDEX idx_subscriptions_tenant_user;
+
+ALTER TABLE subscriptions DROP COLUMN tenant_id;
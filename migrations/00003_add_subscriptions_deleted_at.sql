@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE subscriptions ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE INDEX idx_subscriptions_deleted_at ON subscriptions(deleted_at);
+
+-- +goose Down
+DROP INDEX idx_subscriptions_deleted_at;
+
+ALTER TABLE subscriptions DROP COLUMN deleted_at;
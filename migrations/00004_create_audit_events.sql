@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    request_id VARCHAR(128) NULL,
+    changes TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_events_tenant_entity ON audit_events(tenant_id, entity_type, entity_id, created_at);
+CREATE INDEX idx_audit_events_tenant_created ON audit_events(tenant_id, created_at);
+
+-- +goose Down
+DROP TABLE audit_events;
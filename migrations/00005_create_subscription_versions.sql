@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE subscription_versions (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL,
+    tenant_id UUID NOT NULL,
+    service_name VARCHAR(100) NOT NULL,
+    price NUMERIC(10,2) NOT NULL,
+    user_id UUID NOT NULL,
+    start_date TIMESTAMP NOT NULL,
+    end_date TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP NULL
+);
+
+CREATE INDEX idx_subscription_versions_subscription ON subscription_versions(subscription_id, valid_from);
+CREATE INDEX idx_subscription_versions_tenant_valid ON subscription_versions(tenant_id, valid_from, valid_to);
+
+-- Earlier edits were not recorded, so existing rows start with their current
+-- state as of creation. The subscription ID doubles as the first version ID.
+INSERT INTO subscription_versions
+    (id, subscription_id, tenant_id, service_name, price, user_id, start_date, end_date, created_at, valid_from, valid_to)
+SELECT id, id, tenant_id, service_name, price, user_id, start_date, end_date, created_at, created_at, deleted_at
+FROM subscriptions;
+
+-- +goose Down
+DROP TABLE subscription_versions;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE subscription_prices (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL,
+    tenant_id UUID NOT NULL,
+    price NUMERIC(10,2) NOT NULL,
+    effective_from TIMESTAMP NOT NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP NULL
+);
+
+CREATE INDEX idx_subscription_prices_subscription ON subscription_prices(subscription_id, effective_from);
+
+-- Existing subscriptions were billed at their current price throughout.
+-- The subscription ID doubles as the first price entry ID.
+INSERT INTO subscription_prices (id, subscription_id, tenant_id, price, effective_from, valid_from, valid_to)
+SELECT id, id, tenant_id, price, start_date, created_at, NULL
+FROM subscriptions;
+
+-- +goose Down
+DROP TABLE subscription_prices;
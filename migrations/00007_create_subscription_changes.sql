@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE subscription_changes (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL,
+    tenant_id UUID NOT NULL,
+    effective_from TIMESTAMP NOT NULL,
+    price NUMERIC(10,2) NULL,
+    service_name VARCHAR(100) NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    applied_at TIMESTAMP NULL,
+    cancelled_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_subscription_changes_subscription ON subscription_changes(subscription_id, effective_from);
+CREATE INDEX idx_subscription_changes_due ON subscription_changes(effective_from, applied_at, cancelled_at);
+
+-- +goose Down
+DROP TABLE subscription_changes;
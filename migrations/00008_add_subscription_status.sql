@@ -0,0 +1,45 @@
+-- +goose Up
+ALTER TABLE subscriptions ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE subscriptions ADD COLUMN cancel_at TIMESTAMP NULL;
+ALTER TABLE subscriptions ADD COLUMN cancelled_at TIMESTAMP NULL;
+ALTER TABLE subscriptions ADD COLUMN cancellation_reason VARCHAR(500) NULL;
+
+CREATE INDEX idx_subscriptions_tenant_status ON subscriptions(tenant_id, status);
+
+ALTER TABLE subscription_versions ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE subscription_versions ADD COLUMN cancel_at TIMESTAMP NULL;
+ALTER TABLE subscription_versions ADD COLUMN cancelled_at TIMESTAMP NULL;
+ALTER TABLE subscription_versions ADD COLUMN cancellation_reason VARCHAR(500) NULL;
+
+CREATE TABLE subscription_statuses (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL,
+    tenant_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    effective_from TIMESTAMP NOT NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP NULL
+);
+
+CREATE INDEX idx_subscription_statuses_subscription ON subscription_statuses(subscription_id, effective_from);
+
+-- Existing subscriptions have been active throughout. The subscription ID
+-- doubles as the first status entry ID.
+INSERT INTO subscription_statuses (id, subscription_id, tenant_id, status, effective_from, valid_from, valid_to)
+SELECT id, id, tenant_id, 'active', start_date, created_at, NULL
+FROM subscriptions;
+
+-- +goose Down
+DROP TABLE subscription_statuses;
+
+ALTER TABLE subscription_versions DROP COLUMN cancellation_reason;
+ALTER TABLE subscription_versions DROP COLUMN cancelled_at;
+ALTER TABLE subscription_versions DROP COLUMN cancel_at;
+ALTER TABLE subscription_versions DROP COLUMN status;
+
+DROP INDEX idx_subscriptions_tenant_status;
+
+ALTER TABLE subscriptions DROP COLUMN cancellation_reason;
+ALTER TABLE subscriptions DROP COLUMN cancelled_at;
+ALTER TABLE subscriptions DROP COLUMN cancel_at;
+ALTER TABLE subscriptions DROP COLUMN status;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE subscriptions ADD COLUMN trial_start TIMESTAMP NULL;
+ALTER TABLE subscriptions ADD COLUMN trial_end TIMESTAMP NULL;
+ALTER TABLE subscriptions ADD COLUMN trial_price NUMERIC(10,2) NULL;
+
+CREATE INDEX idx_subscriptions_tenant_trial_end ON subscriptions(tenant_id, trial_end);
+
+ALTER TABLE subscription_versions ADD COLUMN trial_start TIMESTAMP NULL;
+ALTER TABLE subscription_versions ADD COLUMN trial_end TIMESTAMP NULL;
+ALTER TABLE subscription_versions ADD COLUMN trial_price NUMERIC(10,2) NULL;
+
+-- +goose Down
+ALTER TABLE subscription_versions DROP COLUMN trial_price;
+ALTER TABLE subscription_versions DROP COLUMN trial_end;
+ALTER TABLE subscription_versions DROP COLUMN trial_start;
+
+DROP INDEX idx_subscriptions_tenant_trial_end;
+
+ALTER TABLE subscriptions DROP COLUMN trial_price;
+ALTER TABLE subscriptions DROP COLUMN trial_end;
+ALTER TABLE subscriptions DROP COLUMN trial_start;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE subscription_discounts (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL,
+    tenant_id UUID NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    amount NUMERIC(10,2) NOT NULL,
+    start_date TIMESTAMP NOT NULL,
+    end_date TIMESTAMP NULL,
+    cycles INTEGER NULL,
+    description VARCHAR(200) NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP NULL
+);
+
+CREATE INDEX idx_subscription_discounts_subscription ON subscription_discounts(subscription_id, valid_from);
+
+-- +goose Down
+DROP TABLE subscription_discounts;
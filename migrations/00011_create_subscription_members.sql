@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE subscription_members (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL,
+    tenant_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    split VARCHAR(10) NOT NULL,
+    share NUMERIC(10,2) NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP NULL
+);
+
+CREATE INDEX idx_subscription_members_subscription ON subscription_members(subscription_id, valid_from);
+CREATE INDEX idx_subscription_members_tenant_user ON subscription_members(tenant_id, user_id);
+
+-- +goose Down
+DROP TABLE subscription_members;
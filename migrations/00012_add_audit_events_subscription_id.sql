@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE audit_events ADD COLUMN subscription_id UUID NULL;
+
+UPDATE audit_events SET subscription_id = entity_id WHERE entity_type = 'subscription';
+UPDATE audit_events SET subscription_id = (
+    SELECT subscription_id FROM subscription_changes WHERE subscription_changes.id = audit_events.entity_id
+) WHERE entity_type = 'subscription_change';
+UPDATE audit_events SET subscription_id = (
+    SELECT subscription_id FROM subscription_discounts WHERE subscription_discounts.id = audit_events.entity_id
+) WHERE entity_type = 'subscription_discount';
+UPDATE audit_events SET subscription_id = (
+    SELECT subscription_id FROM subscription_members WHERE subscription_members.id = audit_events.entity_id
+) WHERE entity_type = 'subscription_member';
+
+CREATE INDEX idx_audit_events_tenant_subscription ON audit_events(tenant_id, subscription_id, created_at);
+
+-- +goose Down
+DROP INDEX idx_audit_events_tenant_subscription;
+ALTER TABLE audit_events DROP COLUMN subscription_id;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE audit_events ADD COLUMN actor_claimed VARCHAR(100) NULL;
+
+-- Actors recorded so far came from the unverified X-Actor header; only the
+-- scheduler, which runs without a request, set its own.
+UPDATE audit_events SET actor_claimed = actor, actor = 'anonymous'
+WHERE actor <> 'anonymous' AND NOT (actor = 'scheduler' AND (request_id IS NULL OR request_id = ''));
+
+-- +goose Down
+UPDATE audit_events SET actor = actor_claimed WHERE actor = 'anonymous' AND actor_claimed IS NOT NULL;
+ALTER TABLE audit_events DROP COLUMN actor_claimed;
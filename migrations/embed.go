@@ -0,0 +1,14 @@
+// Package migrations embeds the SQL migrations so the binary does not depend
+// on the working directory it is started from.
+//
+// Migrations in the root are shared by every dialect. A file in a dialect's
+// directory, like sqlite/00001_create_subscriptions.sql, replaces the shared
+// file of the same name where the SQL cannot be written portably.
+package migrations
+
+import "embed"
+
+// FS holds every migration file shipped with the binary.
+//
+//go:embed *.sql sqlite/*.sql
+var FS embed.FS
@@ -0,0 +1,19 @@
+-- SQLite has no gen_random_uuid(), so this replaces the shared 00001 without
+-- the id default. IDs are generated in Go on every dialect.
+-- +goose Up
+CREATE TABLE subscriptions (
+    id UUID PRIMARY KEY,
+    service_name VARCHAR(100) NOT NULL,
+    price NUMERIC(10,2) NOT NULL,
+    user_id UUID NOT NULL,
+    start_date TIMESTAMP NOT NULL,
+    end_date TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_subscriptions_user_id ON subscriptions(user_id);
+CREATE INDEX idx_subscriptions_end_date ON subscriptions(end_date);
+
+-- +goose Down
+DROP TABLE subscriptions;
\ No newline at end of file
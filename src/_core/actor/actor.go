@@ -0,0 +1,87 @@
+package actor
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"effective_mobile/src/_core/logger"
+	"effective_mobile/src/_core/response"
+)
+
+const Header = "X-Actor"
+
+// Anonymous is recorded when no verified actor is known.
+const Anonymous = "anonymous"
+
+// Actor names are user IDs, emails or service names.
+var validName = regexp.MustCompile(`^[A-Za-z0-9._@:+-]{1,100}$`)
+
+type (
+	ctxKey   struct{}
+	claimKey struct{}
+)
+
+// WithName records a verified actor, e.g. one taken from a checked
+// credential or the scheduler acting on its own.
+func WithName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, name)
+}
+
+// FromContext returns the verified actor of the request, Anonymous if none
+// is known.
+func FromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(ctxKey{}).(string); ok {
+		return name
+	}
+	return Anonymous
+}
+
+// WithClaim records the actor a request says it acts for without proof.
+func WithClaim(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, claimKey{}, name)
+}
+
+// ClaimFromContext returns the unverified actor named by the request, if any.
+func ClaimFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(claimKey{}).(string)
+	return name
+}
+
+// Middleware resolves who performs the request. The service does not
+// authenticate callers, so the X-Actor header is only recorded as a claim;
+// the actor stays Anonymous unless a middleware in front of this one placed
+// a verified one in the context with WithName.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if name := r.Header.Get(Header); name != "" {
+			if !validName.MatchString(name) {
+				response.Error(w, r, http.StatusBadRequest, "Invalid actor", Header+" must be 1-100 letters, digits or ._@:+-")
+				return
+			}
+			ctx = logger.With(WithClaim(ctx, name), slog.String("actor_claimed", name))
+		}
+
+		ctx = logger.With(ctx, slog.String("actor", FromContext(ctx)))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AdminOnly lets through requests carrying "Authorization: Bearer <token>".
+// With an empty token the routes are closed to everyone.
+func AdminOnly(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				response.Error(w, r, http.StatusForbidden, "Admin access required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package actor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		verified   string
+		wantStatus int
+		wantActor  string
+		wantClaim  string
+	}{
+		{name: "no header", wantStatus: http.StatusOK, wantActor: Anonymous},
+		{name: "header is only a claim", header: "alice@example.com", wantStatus: http.StatusOK, wantActor: Anonymous, wantClaim: "alice@example.com"},
+		{name: "verified actor is kept", header: "bob", verified: "alice", wantStatus: http.StatusOK, wantActor: "alice", wantClaim: "bob"},
+		{name: "invalid header", header: "alice smith", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotActor, gotClaim string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotActor, gotClaim = FromContext(r.Context()), ClaimFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			if tt.verified != "" {
+				req = req.WithContext(WithName(req.Context(), tt.verified))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if gotActor != tt.wantActor || gotClaim != tt.wantClaim {
+				t.Errorf("actor, claim = %q, %q; want %q, %q", gotActor, gotClaim, tt.wantActor, tt.wantClaim)
+			}
+		})
+	}
+}
+
+func TestAdminOnly(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{name: "matching token", token: "secret", authorization: "Bearer secret", want: http.StatusOK},
+		{name: "wrong token", token: "secret", authorization: "Bearer guess", want: http.StatusForbidden},
+		{name: "no bearer prefix", token: "secret", authorization: "secret", want: http.StatusForbidden},
+		{name: "endpoint disabled", authorization: "Bearer ", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := AdminOnly(tt.token)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/api/audit", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -1,28 +1,337 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/yaml.v3"
 )
 
+// FileEnv names the environment variable holding the config file path.
+const FileEnv = "APP_CONFIG_FILE"
+
+const redacted = "******"
+
+var passwordPattern = regexp.MustCompile(`password=\S+`)
+
 type Config struct {
+	// Storage selects the subscriptions backend: postgres, sqlite (a single
+	// file, see SQLite.Path) or memory, which keeps everything in process and
+	// loses it on restart.
+	Storage string `yaml:"storage" envconfig:"APP_STORAGE" validate:"oneof=postgres sqlite memory"`
+
 	API struct {
-		Port int `envconfig:"APP_API_PORT" default:"8080"`
-	}
+		Port              int                      `yaml:"port" envconfig:"APP_API_PORT" validate:"min=1,max=65535"`
+		RequestTimeout    time.Duration            `yaml:"request_timeout" envconfig:"APP_API_REQUEST_TIMEOUT" validate:"min=0"`
+		RouteTimeouts     map[string]time.Duration `yaml:"route_timeouts" envconfig:"APP_API_ROUTE_TIMEOUTS" validate:"dive,min=0"`
+		MaxBodyBytes      int64                    `yaml:"max_body_bytes" envconfig:"APP_API_MAX_BODY_BYTES" validate:"min=0"`
+		ReadTimeout       time.Duration            `yaml:"read_timeout" envconfig:"APP_API_READ_TIMEOUT" validate:"min=0"`
+		ReadHeaderTimeout time.Duration            `yaml:"read_header_timeout" envconfig:"APP_API_READ_HEADER_TIMEOUT" validate:"min=0"`
+		WriteTimeout      time.Duration            `yaml:"write_timeout" envconfig:"APP_API_WRITE_TIMEOUT" validate:"min=0"`
+		IdleTimeout       time.Duration            `yaml:"idle_timeout" envconfig:"APP_API_IDLE_TIMEOUT" validate:"min=0"`
+	} `yaml:"api"`
+	Log struct {
+		Level              string        `yaml:"level" envconfig:"APP_LOG_LEVEL" validate:"oneof=debug info warn error DEBUG INFO WARN ERROR"`
+		SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" envconfig:"APP_LOG_SLOW_QUERY_THRESHOLD" validate:"min=0"`
+	} `yaml:"log"`
+	Health struct {
+		ReadyTimeout      time.Duration `yaml:"ready_timeout" envconfig:"APP_HEALTH_READY_TIMEOUT" validate:"gt=0"`
+		ReportTimeout     time.Duration `yaml:"report_timeout" envconfig:"APP_HEALTH_REPORT_TIMEOUT" validate:"gt=0"`
+		DBTimeout         time.Duration `yaml:"db_timeout" envconfig:"APP_HEALTH_DB_TIMEOUT" validate:"gt=0"`
+		MigrationsTimeout time.Duration `yaml:"migrations_timeout" envconfig:"APP_HEALTH_MIGRATIONS_TIMEOUT" validate:"gt=0"`
+	} `yaml:"health"`
+	Metrics struct {
+		Enabled         bool          `yaml:"enabled" envconfig:"APP_METRICS_ENABLED"`
+		Path            string        `yaml:"path" envconfig:"APP_METRICS_PATH" validate:"startswith=/"`
+		RefreshInterval time.Duration `yaml:"refresh_interval" envconfig:"APP_METRICS_REFRESH_INTERVAL" validate:"gt=0"`
+	} `yaml:"metrics"`
+	Tracing struct {
+		Enabled     bool    `yaml:"enabled" envconfig:"APP_TRACING_ENABLED"`
+		ServiceName string  `yaml:"service_name" envconfig:"APP_TRACING_SERVICE_NAME" validate:"required"`
+		Exporter    string  `yaml:"exporter" envconfig:"APP_TRACING_EXPORTER" validate:"oneof=otlp stdout file"`
+		Endpoint    string  `yaml:"endpoint" envconfig:"APP_TRACING_ENDPOINT" validate:"omitempty,url"`
+		Insecure    bool    `yaml:"insecure" envconfig:"APP_TRACING_INSECURE"`
+		FilePath    string  `yaml:"file_path" envconfig:"APP_TRACING_FILE_PATH" validate:"required_if=Exporter file"`
+		SampleRatio float64 `yaml:"sample_ratio" envconfig:"APP_TRACING_SAMPLE_RATIO" validate:"min=0,max=1"`
+	} `yaml:"tracing"`
+	Shutdown struct {
+		PreStopDelay time.Duration `yaml:"pre_stop_delay" envconfig:"APP_SHUTDOWN_PRE_STOP_DELAY" validate:"min=0"`
+		Timeout      time.Duration `yaml:"timeout" envconfig:"APP_SHUTDOWN_TIMEOUT" validate:"gt=0"`
+	} `yaml:"shutdown"`
 	DB struct {
-		Host     string `envconfig:"APP_DB_HOST" default:"pg"`
-		Name     string `envconfig:"APP_DB_NAME" default:"postgres"`
-		User     string `envconfig:"APP_DB_USER" default:"postgres"`
-		Schema   string `envconfig:"APP_DB_SCHEMA" default:"public"`
-		Port     string `envconfig:"APP_DB_PORT" default:"5432"`
-		Password string `envconfig:"APP_DB_PASSWORD" default:"postgres"`
-	}
+		// URL is a full connection string; when set the fields below that
+		// describe the connection are ignored.
+		URL             string        `yaml:"url" envconfig:"DATABASE_URL" validate:"omitempty,url"`
+		Host            string        `yaml:"host" envconfig:"APP_DB_HOST" validate:"required_without=URL"`
+		Name            string        `yaml:"name" envconfig:"APP_DB_NAME" validate:"required_without=URL"`
+		User            string        `yaml:"user" envconfig:"APP_DB_USER" validate:"required_without=URL"`
+		Schema          string        `yaml:"schema" envconfig:"APP_DB_SCHEMA" validate:"required"`
+		Port            string        `yaml:"port" envconfig:"APP_DB_PORT" validate:"required_without=URL,omitempty,numeric"`
+		Password        string        `yaml:"password" envconfig:"APP_DB_PASSWORD"`
+		SSLMode         string        `yaml:"sslmode" envconfig:"APP_DB_SSLMODE" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+		SSLRootCert     string        `yaml:"sslrootcert" envconfig:"APP_DB_SSLROOTCERT" validate:"omitempty,file"`
+		SSLCert         string        `yaml:"sslcert" envconfig:"APP_DB_SSLCERT" validate:"required_with=SSLKey,omitempty,file"`
+		SSLKey          string        `yaml:"sslkey" envconfig:"APP_DB_SSLKEY" validate:"required_with=SSLCert,omitempty,file"`
+		MaxIdleConns    int           `yaml:"max_idle_conns" envconfig:"APP_DB_MAX_IDLE_CONNS" validate:"min=0,ltefield=MaxOpenConns"`
+		MaxOpenConns    int           `yaml:"max_open_conns" envconfig:"APP_DB_MAX_OPEN_CONNS" validate:"min=1"`
+		ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" envconfig:"APP_DB_CONN_MAX_LIFETIME" validate:"min=0"`
+		ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" envconfig:"APP_DB_CONN_MAX_IDLE_TIME" validate:"min=0"`
+		// Startup keeps retrying the first connection until ConnectTimeout,
+		// waiting from ConnectRetryBase up to ConnectRetryMax between attempts.
+		ConnectTimeout   time.Duration `yaml:"connect_timeout" envconfig:"APP_DB_CONNECT_TIMEOUT" validate:"min=0"`
+		ConnectRetryBase time.Duration `yaml:"connect_retry_base" envconfig:"APP_DB_CONNECT_RETRY_BASE" validate:"gt=0"`
+		ConnectRetryMax  time.Duration `yaml:"connect_retry_max" envconfig:"APP_DB_CONNECT_RETRY_MAX" validate:"gtefield=ConnectRetryBase"`
+		// Replicas are Postgres DSNs serving List, GetByID and summary reads.
+		// A client that wrote within ReadYourWritesWindow reads from the
+		// primary instead; replicas are pinged every ReplicaCheckInterval.
+		Replicas             []string      `yaml:"replicas" envconfig:"APP_DB_REPLICAS" validate:"dive,required"`
+		ReadYourWritesWindow time.Duration `yaml:"read_your_writes_window" envconfig:"APP_DB_READ_YOUR_WRITES_WINDOW" validate:"min=0"`
+		ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" envconfig:"APP_DB_REPLICA_CHECK_INTERVAL" validate:"gt=0"`
+	} `yaml:"db"`
+	SQLite struct {
+		Path string `yaml:"path" envconfig:"APP_SQLITE_PATH" validate:"required"`
+	} `yaml:"sqlite"`
+	Migrations struct {
+		// Policy decides what startup does with the schema: auto applies
+		// pending migrations, verify only compares versions and skip does
+		// neither. OnMismatch picks how verify reacts to a version mismatch:
+		// fail refuses to start, not-ready starts with readiness failing.
+		Policy     string `yaml:"policy" envconfig:"APP_MIGRATIONS_POLICY" validate:"oneof=auto verify skip"`
+		OnMismatch string `yaml:"on_mismatch" envconfig:"APP_MIGRATIONS_ON_MISMATCH" validate:"oneof=fail not-ready"`
+	} `yaml:"migrations"`
+	Trash struct {
+		// Deleted subscriptions are purged for good once they have been in
+		// the trash for Retention; zero keeps them forever.
+		Retention     time.Duration `yaml:"retention" envconfig:"APP_TRASH_RETENTION" validate:"min=0"`
+		PurgeInterval time.Duration `yaml:"purge_interval" envconfig:"APP_TRASH_PURGE_INTERVAL" validate:"gt=0"`
+	} `yaml:"trash"`
+	Scheduler struct {
+		// Interval is how often scheduled changes that have come due are
+		// applied and subscription statuses (cancellations, trials, expiry) are
+		// advanced.
+		Interval time.Duration `yaml:"interval" envconfig:"APP_SCHEDULER_INTERVAL" validate:"gt=0"`
+	} `yaml:"scheduler"`
+	Audit struct {
+		// AdminToken is the bearer token required by the tenant-wide audit
+		// log endpoint; empty disables the endpoint.
+		AdminToken string `yaml:"admin_token" envconfig:"APP_AUDIT_ADMIN_TOKEN"`
+	} `yaml:"audit"`
+	CORS struct {
+		AllowedOrigins   []string `yaml:"allowed_origins" envconfig:"APP_CORS_ALLOWED_ORIGINS"`
+		AllowedMethods   []string `yaml:"allowed_methods" envconfig:"APP_CORS_ALLOWED_METHODS" validate:"dive,oneof=GET POST PUT PATCH DELETE OPTIONS HEAD"`
+		AllowedHeaders   []string `yaml:"allowed_headers" envconfig:"APP_CORS_ALLOWED_HEADERS"`
+		ExposedHeaders   []string `yaml:"exposed_headers" envconfig:"APP_CORS_EXPOSED_HEADERS"`
+		AllowCredentials bool     `yaml:"allow_credentials" envconfig:"APP_CORS_ALLOW_CREDENTIALS"`
+		MaxAge           int      `yaml:"max_age" envconfig:"APP_CORS_MAX_AGE" validate:"min=0"`
+	} `yaml:"cors"`
+	Tenant struct {
+		// Default is the tenant of requests without an X-Tenant-ID header;
+		// it is required unless the header is trusted.
+		Default string `yaml:"default" envconfig:"APP_TENANT_DEFAULT" validate:"required_unless=TrustHeader true,omitempty,uuid"`
+		// TrustHeader accepts the tenant named by the X-Tenant-ID header. The
+		// header is not authenticated, so only set it behind a gateway that
+		// sets it for the caller.
+		TrustHeader bool `yaml:"trust_header" envconfig:"APP_TENANT_TRUST_HEADER"`
+	} `yaml:"tenant"`
+}
+
+// Default returns the configuration used when neither the config file nor
+// the environment set a value.
+func Default() *Config {
+	var cfg Config
+
+	cfg.Storage = "postgres"
+
+	cfg.API.Port = 8080
+	cfg.API.RequestTimeout = 10 * time.Second
+	cfg.API.MaxBodyBytes = 1 << 20
+	cfg.API.ReadTimeout = 15 * time.Second
+	cfg.API.ReadHeaderTimeout = 5 * time.Second
+	cfg.API.WriteTimeout = 15 * time.Second
+	cfg.API.IdleTimeout = 60 * time.Second
+
+	cfg.Log.Level = "info"
+	cfg.Log.SlowQueryThreshold = 200 * time.Millisecond
+
+	cfg.Health.ReadyTimeout = 2 * time.Second
+	cfg.Health.ReportTimeout = 5 * time.Second
+	cfg.Health.DBTimeout = time.Second
+	cfg.Health.MigrationsTimeout = 2 * time.Second
+
+	cfg.Metrics.Enabled = true
+	cfg.Metrics.Path = "/metrics"
+	cfg.Metrics.RefreshInterval = time.Minute
+
+	cfg.Tracing.ServiceName = "subscriptions"
+	cfg.Tracing.Exporter = "otlp"
+	cfg.Tracing.FilePath = "traces.json"
+	cfg.Tracing.SampleRatio = 1
+
+	cfg.Shutdown.Timeout = 30 * time.Second
+
+	cfg.DB.Host = "pg"
+	cfg.DB.Name = "postgres"
+	cfg.DB.User = "postgres"
+	cfg.DB.Schema = "public"
+	cfg.DB.Port = "5432"
+	cfg.DB.Password = "postgres"
+	cfg.DB.SSLMode = "disable"
+	cfg.DB.MaxIdleConns = 10
+	cfg.DB.MaxOpenConns = 100
+	cfg.DB.ConnMaxLifetime = time.Hour
+	cfg.DB.ConnectTimeout = 30 * time.Second
+	cfg.DB.ConnectRetryBase = 500 * time.Millisecond
+	cfg.DB.ConnectRetryMax = 5 * time.Second
+	cfg.DB.ReadYourWritesWindow = 5 * time.Second
+	cfg.DB.ReplicaCheckInterval = 10 * time.Second
+
+	cfg.SQLite.Path = "subscriptions.db"
+
+	cfg.Migrations.Policy = "auto"
+	cfg.Migrations.OnMismatch = "fail"
+
+	cfg.Trash.Retention = 30 * 24 * time.Hour
+	cfg.Trash.PurgeInterval = time.Hour
+
+	cfg.Scheduler.Interval = time.Hour
+
+	cfg.CORS.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	cfg.CORS.AllowedHeaders = []string{"Content-Type", "Authorization", "X-Tenant-ID", "X-Request-ID", "X-Read-Consistency", "X-Actor"}
+	cfg.CORS.ExposedHeaders = []string{"ETag", "Link", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "X-Request-ID"}
+	cfg.CORS.MaxAge = 600
+
+	return &cfg
 }
 
+// Load reads the config file named by APP_CONFIG_FILE, if any.
 func Load() (*Config, error) {
+	return LoadFile("")
+}
+
+// LoadFile layers the configuration: defaults, then the YAML file at path
+// (APP_CONFIG_FILE when path is empty, skipped when both are), then
+// environment variables. The result is validated and every invalid field is
+// reported at once.
+func LoadFile(path string) (*Config, error) {
 	_ = godotenv.Load()
 
-	var cfg Config
-	err := envconfig.Process("", &cfg)
-	return &cfg, err
+	if path == "" {
+		path = os.Getenv(FileEnv)
+	}
+
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error read config file: %w", err)
+		}
+
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("error parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := envconfig.Process("", cfg); err != nil {
+		return nil, fmt.Errorf("error read environment: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks every field and lists all invalid ones in the error.
+func (c *Config) Validate() error {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("yaml"), ",", 2)[0]
+		if env := field.Tag.Get("envconfig"); env != "" {
+			name += " (" + env + ")"
+		}
+		return name
+	})
+
+	err := validate.Struct(c)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	lines := make([]string, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		field := strings.TrimPrefix(fe.Namespace(), "Config.")
+		rule := fe.Tag()
+		if fe.Param() != "" {
+			rule += "=" + fe.Param()
+		}
+		lines = append(lines, fmt.Sprintf("  - %s: must satisfy %q, got %v", field, rule, fe.Value()))
+	}
+
+	return fmt.Errorf("invalid config:\n%s", strings.Join(lines, "\n"))
+}
+
+// Redacted returns a copy safe to print: passwords, including the one in the
+// database URL, and the audit admin token are masked.
+func (c *Config) Redacted() *Config {
+	clone := *c
+
+	if clone.DB.Password != "" {
+		clone.DB.Password = redacted
+	}
+	clone.DB.URL = redactURL(clone.DB.URL)
+	clone.DB.Replicas = make([]string, len(c.DB.Replicas))
+	for i, dsn := range c.DB.Replicas {
+		clone.DB.Replicas[i] = redactDSN(dsn)
+	}
+	if clone.Audit.AdminToken != "" {
+		clone.Audit.AdminToken = redacted
+	}
+
+	return &clone
+}
+
+// YAML renders the configuration in the config file format.
+func (c *Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// redactDSN masks the password in a URL or key/value connection string.
+func redactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		return redactURL(dsn)
+	}
+	return passwordPattern.ReplaceAllString(dsn, "password="+redacted)
+}
+
+func redactURL(raw string) string {
+	if raw == "" {
+		return raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+
+	return u.Redacted()
 }
@@ -1,52 +1,143 @@
 package db
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"net/url"
+	"log/slog"
+	"strings"
 	"time"
 
 	"effective_mobile/src/_core/config"
+	"effective_mobile/src/_core/logger"
 
+	"github.com/glebarez/sqlite"
+	"github.com/sethvargo/go-retry"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
-func Connect() (*gorm.DB, error) {
-	cfg, err := config.Load()
+// Dialect names as reported by gorm.Dialector.Name.
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
 
-	if err != nil {
-		log.Fatalf("Error load config: %v", err)
-	}
+// Connect opens the connection pool shared by the application and the
+// migrations, on Postgres or SQLite depending on cfg.Storage. While the
+// database is unreachable it retries with exponential backoff and jitter
+// until cfg.DB.ConnectTimeout elapses.
+func Connect(ctx context.Context, cfg *config.Config) (*gorm.DB, error) {
+	backoff := retry.NewExponential(cfg.DB.ConnectRetryBase)
+	backoff = retry.WithCappedDuration(cfg.DB.ConnectRetryMax, backoff)
+	backoff = retry.WithJitterPercent(20, backoff)
+	backoff = retry.WithMaxDuration(cfg.DB.ConnectTimeout, backoff)
 
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s search_path=%s sslmode=disable TimeZone=UTC",
-		cfg.DB.Host,
-		cfg.DB.User,
-		url.QueryEscape(cfg.DB.Password),
-		cfg.DB.Name,
-		cfg.DB.Port,
-		cfg.DB.Schema,
-	)
+	attempt := 0
+	db, err := retry.DoValue(ctx, backoff, func(ctx context.Context) (*gorm.DB, error) {
+		attempt++
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		PrepareStmt: true,
+		// Failed attempts are reported below, keep GORM quiet about them.
+		db, err := gorm.Open(dialector(cfg), &gorm.Config{
+			PrepareStmt: true,
+			Logger:      gormlogger.Discard,
+			// Timestamps GORM fills in (created, updated, deleted) are stored
+			// in UTC like every other date, so they compare correctly.
+			NowFunc: func() time.Time { return time.Now().UTC() },
+		})
+		if err != nil {
+			// gorm.Open returns the pool it opened even when the ping
+			// fails; close it so every retry does not leak one.
+			if db != nil {
+				Close(db)
+			}
+			slog.Warn("Database is not reachable yet", slog.Int("attempt", attempt), slog.Any("error", err))
+			return nil, retry.RetryableError(err)
+		}
+		return db, nil
 	})
 	if err != nil {
-		panic("Error connect DB")
+		return nil, fmt.Errorf("error connect DB after %d attempts: %w", attempt, err)
 	}
 
-	sqlDB, err := db.DB()
+	db.Logger = logger.NewGormLogger(cfg.Log.SlowQueryThreshold)
 
+	sqlDB, err := db.DB()
 	if err != nil {
-		panic("Error get underlying sql.DB")
+		return nil, fmt.Errorf("error get underlying sql.DB: %w", err)
+	}
+
+	if cfg.Storage == SQLite {
+		// SQLite allows a single writer; one connection avoids "database is
+		// locked" errors and keeps ":memory:" databases shared.
+		sqlDB.SetMaxOpenConns(1)
+		slog.Info("Connected DB", slog.String("path", cfg.SQLite.Path))
+		return db, nil
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	sqlDB.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.DB.ConnMaxIdleTime)
 
-	fmt.Println("Connected DB")
+	slog.Info("Connected DB", slog.String("host", cfg.DB.Host), slog.String("name", cfg.DB.Name))
 
 	return db, nil
 }
+
+// Close releases the connection pool behind db.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Name identifies the database in logs and metrics.
+func Name(cfg *config.Config) string {
+	if cfg.Storage == SQLite {
+		return cfg.SQLite.Path
+	}
+	return cfg.DB.Name
+}
+
+func dialector(cfg *config.Config) gorm.Dialector {
+	if cfg.Storage == SQLite {
+		return sqlite.Open(cfg.SQLite.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	}
+	return postgres.Open(DSN(cfg))
+}
+
+// DSN builds the Postgres connection string. DATABASE_URL is used verbatim
+// when set.
+func DSN(cfg *config.Config) string {
+	if cfg.DB.URL != "" {
+		return cfg.DB.URL
+	}
+
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s search_path=%s sslmode=%s TimeZone=UTC",
+		quoteDSN(cfg.DB.Host),
+		quoteDSN(cfg.DB.User),
+		quoteDSN(cfg.DB.Password),
+		quoteDSN(cfg.DB.Name),
+		quoteDSN(cfg.DB.Port),
+		quoteDSN(cfg.DB.Schema),
+		quoteDSN(cfg.DB.SSLMode),
+	)
+	if cfg.DB.SSLRootCert != "" {
+		dsn += " sslrootcert=" + quoteDSN(cfg.DB.SSLRootCert)
+	}
+	if cfg.DB.SSLCert != "" {
+		dsn += " sslcert=" + quoteDSN(cfg.DB.SSLCert) + " sslkey=" + quoteDSN(cfg.DB.SSLKey)
+	}
+
+	return dsn
+}
+
+// quoteDSN quotes a key=value connection string value the way libpq reads
+// it: in single quotes, with backslashes and single quotes escaped.
+func quoteDSN(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"effective_mobile/src/_core/config"
+)
+
+func TestConnectClosesFailedAttempts(t *testing.T) {
+	cfg := config.Default()
+	cfg.DB.Host = "127.0.0.1"
+	cfg.DB.Port = "1"
+	cfg.DB.ConnectRetryBase = time.Millisecond
+	cfg.DB.ConnectRetryMax = time.Millisecond
+	cfg.DB.ConnectTimeout = 100 * time.Millisecond
+
+	before := openPools(t)
+	if _, err := Connect(context.Background(), cfg); err == nil {
+		t.Fatal("Connect to a closed port succeeded")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if after := openPools(t); after > before {
+		t.Errorf("%d connection pools open after the failed attempts, %d before", after, before)
+	}
+}
+
+// openPools counts the live sql.DB pools by their connection opener
+// goroutines, which run until the pool is closed.
+func openPools(t *testing.T) int {
+	t.Helper()
+	var stacks bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&stacks, 2); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(stacks.Bytes(), []byte("database/sql.(*DB).connectionOpener"))
+}
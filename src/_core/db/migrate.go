@@ -1,59 +1,306 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	"effective_mobile/src/_core/config"
+	"errors"
 	"fmt"
-	"log"
-	"net/url"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
 	"strings"
 
-	_ "github.com/lib/pq"
+	"effective_mobile/migrations"
+
 	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+	"github.com/pressly/goose/v3/lock"
+	"gorm.io/gorm"
 )
 
-func RunMigrations(direction string) error {
-	direction = strings.ToLower(direction)
-	if direction != "up" && direction != "down" {
-		return fmt.Errorf("invalid direction: %s (allowed: up/down)", direction)
-	}
+const migrationsTable = "_migrations"
 
-	cfg, err := config.Load()
+// NewMigrator returns a goose provider over the embedded migrations for the
+// dialect of db. On Postgres every run holds a session advisory lock, so
+// replicas starting at the same time apply migrations one after another
+// instead of racing; SQLite is single-node and needs none.
+func NewMigrator(db *gorm.DB) (*goose.Provider, error) {
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("error load config: %w", err)
+		return nil, fmt.Errorf("error get underlying sql.DB: %w", err)
 	}
 
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s search_path=%s sslmode=disable TimeZone=UTC",
-		cfg.DB.Host,
-		cfg.DB.User,
-		url.QueryEscape(cfg.DB.Password),
-		cfg.DB.Name,
-		cfg.DB.Port,
-		cfg.DB.Schema,
-	)
+	var opts []goose.ProviderOption
+	switch db.Dialector.Name() {
+	case Postgres:
+		store, err := database.NewStore(database.DialectPostgres, migrationsTable)
+		if err != nil {
+			return nil, fmt.Errorf("error create migration store: %w", err)
+		}
+		locker, err := lock.NewPostgresSessionLocker()
+		if err != nil {
+			return nil, fmt.Errorf("error create migration lock: %w", err)
+		}
+		opts = append(opts, goose.WithStore(store), goose.WithSessionLocker(locker))
+	case SQLite:
+		store, err := database.NewStore(database.DialectSQLite3, migrationsTable)
+		if err != nil {
+			return nil, fmt.Errorf("error create migration store: %w", err)
+		}
+		opts = append(opts, goose.WithStore(store))
+	default:
+		return nil, fmt.Errorf("unsupported dialect: %s", db.Dialector.Name())
+	}
 
-	db, err := sql.Open("postgres", dsn)
+	provider, err := goose.NewProvider("", sqlDB, migrationFS(db.Dialector.Name()), opts...)
 	if err != nil {
-		return fmt.Errorf("error connect DB: %w", err)
+		return nil, fmt.Errorf("error create migrator: %w", err)
 	}
-	defer db.Close()
 
-	goose.SetBaseFS(nil)
-	goose.SetTableName("_migrations")
+	return provider, nil
+}
+
+// RunMigrations applies or reverts migrations over db, the pool returned by
+// Connect.
+func RunMigrations(ctx context.Context, db *gorm.DB, direction string) error {
+	direction = strings.ToLower(direction)
+	if direction != "up" && direction != "down" {
+		return fmt.Errorf("invalid direction: %s (allowed: up/down)", direction)
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
 
 	switch direction {
 	case "up":
-		if err := goose.Up(db, "migrations"); err != nil {
+		results, err := migrator.Up(ctx)
+		if err != nil {
 			return fmt.Errorf("error applying migrations: %w", err)
 		}
-		log.Println("Migrations applied successfully!")
+		logResults(results)
+		slog.Info("Migrations applied successfully")
 	case "down":
-		if err := goose.Down(db, "migrations"); err != nil {
+		result, err := migrator.Down(ctx)
+		if err != nil {
 			return fmt.Errorf("error reverting migrations: %w", err)
 		}
-		log.Println("Migrations reverted successfully!")
+		logResults([]*goose.MigrationResult{result})
+		slog.Info("Migrations reverted successfully")
+	}
+
+	return nil
+}
+
+// MigrationVersions returns the schema version recorded in the database and
+// the latest version embedded in the binary. It only reads: unlike goose,
+// which creates and seeds the version table on first use, a database without
+// the table reports version 0, so verify and skip leave a fresh database
+// untouched. Older goose releases record a rollback as a new row with
+// is_applied false instead of deleting the version, so a version counts only
+// while its latest row is applied.
+func MigrationVersions(ctx context.Context, db *gorm.DB) (current, expected int64, err error) {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return 0, 0, err
+	}
+	if sources := migrator.ListSources(); len(sources) > 0 {
+		expected = sources[len(sources)-1].Version
+	}
+
+	tx := db.WithContext(ctx)
+	if !tx.Migrator().HasTable(migrationsTable) {
+		return 0, expected, nil
+	}
+
+	var version sql.NullInt64
+	query := "SELECT MAX(version_id) FROM " + migrationsTable + " m WHERE is_applied" +
+		" AND id = (SELECT MAX(id) FROM " + migrationsTable + " WHERE version_id = m.version_id)"
+	if err := tx.Raw(query).Scan(&version).Error; err != nil {
+		return 0, expected, fmt.Errorf("error get DB version: %w", err)
+	}
+
+	return version.Int64, expected, nil
+}
+
+func logResults(results []*goose.MigrationResult) {
+	for _, res := range results {
+		slog.Info("Migration done",
+			slog.Int64("version", res.Source.Version),
+			slog.String("direction", res.Direction),
+			slog.Duration("duration", res.Duration),
+		)
+	}
+}
+
+// dialectFS serves the migrations of one dialect: a file in the dialect's
+// directory replaces the shared file of the same name.
+type dialectFS struct {
+	fs.FS
+	dir string
+}
+
+func migrationFS(dialect string) fs.FS {
+	return dialectFS{FS: migrations.FS, dir: dialect}
+}
+
+func (f dialectFS) Open(name string) (fs.File, error) {
+	if name != "." {
+		if file, err := f.FS.Open(path.Join(f.dir, name)); err == nil {
+			return file, nil
+		}
+	}
+	return f.FS.Open(name)
+}
+
+// MigrationSQL returns the Up or Down section of an embedded SQL migration
+// as it is run on dialect, with the goose annotations stripped.
+func MigrationSQL(dialect, source string, up bool) (string, error) {
+	return sectionSQL(migrationFS(dialect), source, up)
+}
+
+func sectionSQL(fsys fs.FS, source string, up bool) (string, error) {
+	data, err := fs.ReadFile(fsys, source)
+	if err != nil {
+		return "", fmt.Errorf("error read migration %s: %w", source, err)
+	}
+
+	var b strings.Builder
+	inSection := false
+	for _, line := range strings.Split(string(data), "\n") {
+		annotation, ok := gooseAnnotation(line)
+		if !ok {
+			if inSection {
+				b.WriteString(line)
+				b.WriteByte('\n')
+			}
+			continue
+		}
+		switch annotation {
+		case "Up":
+			inSection = up
+		case "Down":
+			inSection = !up
+		}
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+// ValidateMigrations checks the embedded migrations without touching the
+// database: versions must be unique and every SQL file must have a
+// non-empty Up section, an optional Down section after it, and balanced
+// StatementBegin/StatementEnd blocks. Dialect files must replace a shared
+// migration.
+func ValidateMigrations() error {
+	files, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		return fmt.Errorf("error list migrations: %w", err)
+	}
+	if len(files) == 0 {
+		return errors.New("no migrations embedded")
+	}
+
+	var errs []error
+	versions := make(map[int64]string, len(files))
+	for _, file := range files {
+		version, err := goose.NumericComponent(path.Base(file))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+		if other, ok := versions[version]; ok {
+			errs = append(errs, fmt.Errorf("%s: version %d already used by %s", file, version, other))
+		}
+		versions[version] = file
+
+		if err := validateSQL(file); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+		}
+	}
+
+	overrides, err := fs.Glob(migrations.FS, "*/*.sql")
+	if err != nil {
+		return fmt.Errorf("error list dialect migrations: %w", err)
+	}
+	for _, file := range overrides {
+		if !slices.Contains(files, path.Base(file)) {
+			errs = append(errs, fmt.Errorf("%s: replaces no shared migration", file))
+			continue
+		}
+		if err := validateSQL(file); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateSQL(file string) error {
+	data, err := fs.ReadFile(migrations.FS, file)
+	if err != nil {
+		return err
+	}
+
+	var ups, downs, open int
+	for _, line := range strings.Split(string(data), "\n") {
+		annotation, ok := gooseAnnotation(line)
+		if !ok {
+			continue
+		}
+		switch annotation {
+		case "Up":
+			if downs > 0 {
+				return errors.New("Up section after Down section")
+			}
+			ups++
+		case "Down":
+			downs++
+		case "StatementBegin":
+			open++
+		case "StatementEnd":
+			open--
+		}
+		if open < 0 || open > 1 {
+			return errors.New("unbalanced StatementBegin/StatementEnd")
+		}
+	}
+
+	switch {
+	case ups != 1:
+		return fmt.Errorf("expected exactly one Up section, found %d", ups)
+	case downs > 1:
+		return fmt.Errorf("expected at most one Down section, found %d", downs)
+	case open != 0:
+		return errors.New("StatementBegin without StatementEnd")
+	}
+
+	upSQL, err := sectionSQL(migrations.FS, file, true)
+	if err != nil {
+		return err
+	}
+	if upSQL == "" {
+		return errors.New("empty Up section")
 	}
 
 	return nil
 }
+
+// gooseAnnotation reports the annotation on a "-- +goose X" line.
+func gooseAnnotation(line string) (string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "--")
+	if !ok {
+		return "", false
+	}
+	rest, ok = strings.CutPrefix(strings.TrimSpace(rest), "+goose ")
+	if !ok {
+		return "", false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", false
+	}
+	return fields[0], true
+}
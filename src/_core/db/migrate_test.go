@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"effective_mobile/src/_core/config"
+)
+
+func TestMigrationVersions(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.Storage = SQLite
+	cfg.SQLite.Path = filepath.Join(t.TempDir(), "migrations.db")
+
+	gormDB, err := Connect(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close(gormDB) })
+
+	current, expected, err := MigrationVersions(ctx, gormDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != 0 || expected == 0 {
+		t.Errorf("fresh database: current %d, expected %d; want 0 and the latest version", current, expected)
+	}
+	if gormDB.Migrator().HasTable(migrationsTable) {
+		t.Errorf("reading the versions of a fresh database created %s", migrationsTable)
+	}
+
+	if err := RunMigrations(ctx, gormDB, "up"); err != nil {
+		t.Fatal(err)
+	}
+	if current, _, err = MigrationVersions(ctx, gormDB); err != nil || current != expected {
+		t.Errorf("after up: current %d, %v; want %d", current, err, expected)
+	}
+
+	// A rollback recorded by an older goose release.
+	if err := gormDB.Exec("INSERT INTO "+migrationsTable+" (version_id, is_applied) VALUES (?, ?)", expected, false).Error; err != nil {
+		t.Fatal(err)
+	}
+	if current, _, err = MigrationVersions(ctx, gormDB); err != nil || current != expected-1 {
+		t.Errorf("after a legacy rollback: current %d, %v; want %d", current, err, expected-1)
+	}
+
+	// Applying it again records a new applied row.
+	if err := gormDB.Exec("INSERT INTO "+migrationsTable+" (version_id, is_applied) VALUES (?, ?)", expected, true).Error; err != nil {
+		t.Fatal(err)
+	}
+	if current, _, err = MigrationVersions(ctx, gormDB); err != nil || current != expected {
+		t.Errorf("after reapplying: current %d, %v; want %d", current, err, expected)
+	}
+}
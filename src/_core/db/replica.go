@@ -0,0 +1,247 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"effective_mobile/src/_core/config"
+	"effective_mobile/src/_core/logger"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type (
+	primaryKey struct{}
+	txKey      struct{}
+)
+
+// WithPrimary marks ctx so reads made with it go to the primary, e.g. when
+// the client asked for strong consistency or the read precedes a write.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+type replica struct {
+	name    string
+	db      *gorm.DB
+	healthy atomic.Bool
+	checked atomic.Bool
+	lastErr atomic.Value
+}
+
+// Cluster routes reads to healthy replicas in turn and everything else to
+// the primary. A client that wrote recently reads from the primary until
+// the read-your-writes window has passed, so it never sees stale data.
+type Cluster struct {
+	primary  *gorm.DB
+	replicas []*replica
+	next     atomic.Uint64
+	window   time.Duration
+	timeout  time.Duration
+	writes   sync.Map
+}
+
+// NewCluster opens a pool per replica DSN in cfg.DB.Replicas. Replicas start
+// unhealthy and only receive reads after CheckReplicas succeeds for them.
+// They are a Postgres feature; on SQLite the cluster is just the primary.
+func NewCluster(primary *gorm.DB, cfg *config.Config) (*Cluster, error) {
+	c := &Cluster{
+		primary: primary,
+		window:  cfg.DB.ReadYourWritesWindow,
+		timeout: cfg.Health.DBTimeout,
+	}
+	if cfg.Storage == SQLite {
+		return c, nil
+	}
+
+	for i, dsn := range cfg.DB.Replicas {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+			PrepareStmt:          true,
+			DisableAutomaticPing: true,
+			Logger:               logger.NewGormLogger(cfg.Log.SlowQueryThreshold),
+		})
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("error open replica %d: %w", i, err)
+		}
+
+		sqlDB, err := db.DB()
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("error get replica %d sql.DB: %w", i, err)
+		}
+		sqlDB.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+		sqlDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+		sqlDB.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
+		sqlDB.SetConnMaxIdleTime(cfg.DB.ConnMaxIdleTime)
+
+		c.replicas = append(c.replicas, &replica{name: fmt.Sprintf("replica-%d", i), db: db})
+	}
+
+	return c, nil
+}
+
+// Primary returns the handle for writes and consistent reads.
+func (c *Cluster) Primary() *gorm.DB {
+	return c.primary
+}
+
+// Transaction runs fn in a transaction on the primary. Writer and Reader
+// return that transaction for ctx, so every repository involved commits or
+// rolls back together. Nested calls join the outer transaction.
+func (c *Cluster) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return c.primary.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// Writer returns the handle for writes: the transaction in ctx, if any, or
+// the primary.
+func (c *Cluster) Writer(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return c.primary.WithContext(ctx)
+}
+
+// Replicas returns the replica handles, e.g. to register GORM plugins.
+func (c *Cluster) Replicas() []*gorm.DB {
+	dbs := make([]*gorm.DB, len(c.replicas))
+	for i, r := range c.replicas {
+		dbs[i] = r.db
+	}
+	return dbs
+}
+
+// Reader returns the handle for a read-only query made on behalf of client.
+// It falls back to the primary when ctx asks for it, when client wrote
+// within the read-your-writes window, or when no replica is healthy. Inside
+// a transaction it returns the transaction.
+func (c *Cluster) Reader(ctx context.Context, client string) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	if len(c.replicas) == 0 || usePrimary(ctx) || c.wroteRecently(client) {
+		return c.primary
+	}
+
+	start := c.next.Add(1)
+	for i := range uint64(len(c.replicas)) {
+		r := c.replicas[(start+i)%uint64(len(c.replicas))]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+
+	return c.primary
+}
+
+// Wrote records a write by client, pinning its reads to the primary for the
+// read-your-writes window.
+func (c *Cluster) Wrote(client string) {
+	if len(c.replicas) == 0 || c.window == 0 {
+		return
+	}
+	c.writes.Store(client, time.Now().Add(c.window))
+}
+
+func (c *Cluster) wroteRecently(client string) bool {
+	until, ok := c.writes.Load(client)
+	if !ok {
+		return false
+	}
+	if time.Now().Before(until.(time.Time)) {
+		return true
+	}
+	c.writes.CompareAndDelete(client, until)
+	return false
+}
+
+// forgetWrites drops the clients whose read-your-writes window has passed.
+// Clients are named by the caller, so without this a client that never
+// reads again would stay in the map forever.
+func (c *Cluster) forgetWrites(now time.Time) {
+	c.writes.Range(func(client, until any) bool {
+		if !now.Before(until.(time.Time)) {
+			c.writes.CompareAndDelete(client, until)
+		}
+		return true
+	})
+}
+
+// CheckReplicas pings every replica and updates which ones receive reads.
+// Failures are logged when a replica changes state rather than returned, so
+// a replica that stays down does not log on every check. It also forgets
+// the writes whose read-your-writes window has passed.
+func (c *Cluster) CheckReplicas(ctx context.Context) error {
+	c.forgetWrites(time.Now())
+
+	for _, r := range c.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := ping(pingCtx, r.db)
+		cancel()
+
+		healthy := err == nil
+		changed := r.healthy.Swap(healthy) != healthy
+		if first := !r.checked.Swap(true); first || changed {
+			if err != nil {
+				slog.Warn("Replica is down, reading from primary", slog.String("replica", r.name), slog.Any("error", err))
+			} else {
+				slog.Info("Replica is up", slog.String("replica", r.name))
+			}
+		}
+		if err != nil {
+			r.lastErr.Store(err.Error())
+		}
+	}
+	return nil
+}
+
+// ReplicaStatus reports the state seen by the last CheckReplicas call.
+func (c *Cluster) ReplicaStatus() map[string]string {
+	status := make(map[string]string, len(c.replicas))
+	for _, r := range c.replicas {
+		if r.healthy.Load() {
+			status[r.name] = "up"
+			continue
+		}
+		status[r.name] = "down"
+		if msg, ok := r.lastErr.Load().(string); ok {
+			status[r.name] += ": " + msg
+		}
+	}
+	return status
+}
+
+// Close releases the replica pools. The primary is closed by its owner.
+func (c *Cluster) Close() error {
+	var errs []error
+	for _, r := range c.replicas {
+		if err := Close(r.db); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClusterWrites(t *testing.T) {
+	c := &Cluster{replicas: []*replica{{name: "replica-0"}}, window: time.Minute}
+
+	c.Wrote("tenant/alice")
+	c.Wrote("tenant/bob")
+	if !c.wroteRecently("tenant/alice") {
+		t.Error("alice did not write recently, want the primary within the window")
+	}
+	if c.wroteRecently("tenant/carol") {
+		t.Error("carol wrote recently, want no write recorded")
+	}
+
+	c.forgetWrites(time.Now())
+	if n := countWrites(c); n != 2 {
+		t.Errorf("%d writes kept within the window, want 2", n)
+	}
+
+	c.forgetWrites(time.Now().Add(time.Minute))
+	if n := countWrites(c); n != 0 {
+		t.Errorf("%d writes kept after the window, want 0", n)
+	}
+	if c.wroteRecently("tenant/bob") {
+		t.Error("bob wrote recently after the window")
+	}
+}
+
+func TestClusterWritesWithoutReplicas(t *testing.T) {
+	c := &Cluster{window: time.Minute}
+
+	c.Wrote("tenant/alice")
+	if n := countWrites(c); n != 0 {
+		t.Errorf("%d writes recorded without replicas, want 0", n)
+	}
+}
+
+func countWrites(c *Cluster) int {
+	n := 0
+	c.writes.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
@@ -0,0 +1,183 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"effective_mobile/src/_core/response"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc probes a dependency. Details, if any, are included in the /health
+// report next to the check status.
+type CheckFunc func(ctx context.Context) (details any, err error)
+
+type check struct {
+	name    string
+	timeout time.Duration
+	run     CheckFunc
+}
+
+type Options struct {
+	ReadyTimeout  time.Duration
+	ReportTimeout time.Duration
+}
+
+type Health struct {
+	ready  atomic.Bool
+	opts   Options
+	checks []check
+}
+
+type Report struct {
+	// Overall status, "up" when every check passed
+	Status string `json:"status"`
+
+	// Whether the instance accepts traffic
+	Ready bool `json:"ready"`
+
+	// Per dependency results
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	// Check status, "up" or "down"
+	Status string `json:"status"`
+
+	// Time spent on the check in milliseconds
+	LatencyMs float64 `json:"latency_ms"`
+
+	// Failure reason
+	Error string `json:"error,omitempty"`
+
+	// Check specific details
+	Details any `json:"details,omitempty"`
+}
+
+func New(opts Options) *Health {
+	return &Health{opts: opts}
+}
+
+// SetReady marks whether the instance should receive traffic. It is flipped
+// to false first thing on shutdown so the orchestrator stops routing to us.
+func (h *Health) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+func (h *Health) Ready() bool {
+	return h.ready.Load()
+}
+
+// AddCheck registers a dependency probe used by /readyz and /health.
+func (h *Health) AddCheck(name string, timeout time.Duration, run CheckFunc) {
+	h.checks = append(h.checks, check{name: name, timeout: timeout, run: run})
+}
+
+// RegisterRoutes mounts the probes on r. They must stay outside of the API
+// subrouter so tenant resolution and other API middleware never apply.
+func (h *Health) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/healthz", h.Liveness).Methods("GET")
+	r.HandleFunc("/readyz", h.Readiness).Methods("GET")
+	r.HandleFunc("/health", h.Detailed).Methods("GET")
+}
+
+// Liveness reports that the process is running.
+func (h *Health) Liveness(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, map[string]string{"status": StatusUp})
+}
+
+// Readiness reports whether the instance can serve traffic: it is not
+// shutting down and every dependency check passes.
+func (h *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	if !h.Ready() {
+		response.JSON(w, http.StatusServiceUnavailable, map[string]string{"status": StatusDown})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.ReadyTimeout)
+	defer cancel()
+
+	report := h.run(ctx)
+	if report.Status != StatusUp {
+		response.JSON(w, http.StatusServiceUnavailable, map[string]string{"status": StatusDown})
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"status": StatusUp})
+}
+
+// Detailed runs every dependency check and returns its status and latency.
+func (h *Health) Detailed(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.opts.ReportTimeout)
+	defer cancel()
+
+	report := h.run(ctx)
+
+	statusCode := http.StatusOK
+	if report.Status != StatusUp || !report.Ready {
+		statusCode = http.StatusServiceUnavailable
+	}
+	response.JSON(w, statusCode, report)
+}
+
+func (h *Health) run(ctx context.Context) Report {
+	report := Report{
+		Status: StatusUp,
+		Ready:  h.Ready(),
+		Checks: make(map[string]CheckResult, len(h.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range h.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+
+			result := c.execute(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (c check) execute(ctx context.Context) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	started := time.Now()
+	details, err := c.run(ctx)
+	result := CheckResult{
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(started).Microseconds()) / 1000,
+		Details:   details,
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+type ctxKey struct{}
+
+type scopeKey struct{}
+
+// scope collects attributes added while a request is served, so a log line
+// written by an outer middleware (the access log) can include the ones found
+// further down the chain, e.g. the tenant.
+type scope struct {
+	mu   sync.Mutex
+	args []any
+}
+
+// New builds a JSON logger writing to w at the given level and makes it the
+// process default, so the standard log package ends up in the same stream.
+func New(w io.Writer, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	l := slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl}))
+	slog.SetDefault(l)
+
+	return l, nil
+}
+
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the request scoped logger or the default one.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// With adds attributes to the logger carried by ctx.
+func With(ctx context.Context, args ...any) context.Context {
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		s.mu.Lock()
+		s.args = append(s.args, args...)
+		s.mu.Unlock()
+	}
+	return WithContext(ctx, FromContext(ctx).With(args...))
+}
+
+// NewScope starts collecting attributes passed to With on ctx and its
+// children. The returned function reports everything collected so far.
+func NewScope(ctx context.Context) (context.Context, func() []any) {
+	s := &scope{}
+	return context.WithValue(ctx, scopeKey{}, s), func() []any {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return append([]any(nil), s.args...)
+	}
+}
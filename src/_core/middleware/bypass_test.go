@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestBypass(t *testing.T) {
+	probes := mux.NewRouter()
+	probes.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("GET")
+
+	wrapped := 0
+	handler := Bypass(probes, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapped++
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantStatus  int
+		wantWrapped bool
+	}{
+		{name: "probe", method: http.MethodGet, path: "/healthz", wantStatus: http.StatusNoContent},
+		{name: "other route", method: http.MethodGet, path: "/api/subscriptions", wantStatus: http.StatusTeapot, wantWrapped: true},
+		{name: "probe path with another method", method: http.MethodPost, path: "/healthz", wantStatus: http.StatusTeapot, wantWrapped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped = 0
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if (wrapped == 1) != tt.wantWrapped {
+				t.Errorf("served by the wrapped handler = %v, want %v", wrapped == 1, tt.wantWrapped)
+			}
+		})
+	}
+}
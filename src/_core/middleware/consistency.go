@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"effective_mobile/src/_core/db"
+)
+
+// ConsistencyHeader set to "strong" makes the request read from the primary
+// database, e.g. right after a write served by another instance.
+const ConsistencyHeader = "X-Read-Consistency"
+
+func ReadConsistency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get(ConsistencyHeader), "strong") {
+			r = r.WithContext(db.WithPrimary(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type CORSOptions struct {
+	// Exact origins ("https://app.example.com"), wildcard subdomains
+	// ("https://*.example.com") or "*" for any origin
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+type cors struct {
+	anyOrigin        bool
+	origins          map[string]struct{}
+	wildcards        [][2]string
+	allowedMethods   map[string]struct{}
+	methods          string
+	headers          string
+	exposed          string
+	allowCredentials bool
+	maxAge           string
+}
+
+// CORS applies opts to every response. Preflight requests are answered only
+// when router has a route for the requested path and method, everything else
+// is passed through to next.
+func CORS(opts CORSOptions, router *mux.Router) func(http.Handler) http.Handler {
+	c := &cors{
+		origins:          make(map[string]struct{}),
+		allowedMethods:   make(map[string]struct{}),
+		methods:          strings.Join(opts.AllowedMethods, ", "),
+		headers:          strings.Join(opts.AllowedHeaders, ", "),
+		exposed:          strings.Join(opts.ExposedHeaders, ", "),
+		allowCredentials: opts.AllowCredentials,
+	}
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(opts.MaxAge)
+	}
+
+	for _, method := range opts.AllowedMethods {
+		c.allowedMethods[strings.ToUpper(strings.TrimSpace(method))] = struct{}{}
+	}
+
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			c.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			parts := strings.SplitN(origin, "*", 2)
+			c.wildcards = append(c.wildcards, [2]string{parts[0], parts[1]})
+		case origin != "":
+			c.origins[origin] = struct{}{}
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			requestMethod := r.Header.Get("Access-Control-Request-Method")
+			preflight := r.Method == http.MethodOptions && origin != "" && requestMethod != ""
+
+			// Responses differ per origin unless every origin gets "*".
+			if !c.anyOrigin || c.allowCredentials {
+				w.Header().Add("Vary", "Origin")
+			}
+
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+				if !c.hasRoute(router, r, requestMethod) {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				if c.allowOrigin(w, origin) {
+					w.Header().Set("Access-Control-Allow-Methods", c.methods)
+					if c.headers != "" {
+						w.Header().Set("Access-Control-Allow-Headers", c.headers)
+					}
+					if c.maxAge != "" {
+						w.Header().Set("Access-Control-Max-Age", c.maxAge)
+					}
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if origin != "" && c.allowOrigin(w, origin) && c.exposed != "" {
+				w.Header().Set("Access-Control-Expose-Headers", c.exposed)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (c *cors) allowOrigin(w http.ResponseWriter, origin string) bool {
+	if !c.originAllowed(strings.ToLower(origin)) {
+		return false
+	}
+
+	if c.anyOrigin && !c.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+func (c *cors) originAllowed(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	if _, ok := c.origins[origin]; ok {
+		return true
+	}
+	for _, wildcard := range c.wildcards {
+		prefix, suffix := wildcard[0], wildcard[1]
+		if len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) &&
+			strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cors) hasRoute(router *mux.Router, r *http.Request, method string) bool {
+	if _, ok := c.allowedMethods[strings.ToUpper(method)]; !ok {
+		return false
+	}
+
+	probe := r.Clone(r.Context())
+	probe.Method = method
+
+	var match mux.RouteMatch
+	return router.Match(probe, &match)
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func corsHandler(opts CORSOptions) http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/api/subscriptions", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET", "POST")
+	r.HandleFunc("/api/subscriptions/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods("DELETE")
+
+	if opts.AllowedMethods == nil {
+		opts.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	}
+	return CORS(opts, r)(r)
+}
+
+func TestCORSOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    string
+	}{
+		{name: "exact origin", allowed: []string{"https://app.example.com"}, origin: "https://app.example.com", want: "https://app.example.com"},
+		{name: "origin case is ignored", allowed: []string{"https://App.Example.com"}, origin: "https://app.EXAMPLE.com", want: "https://app.EXAMPLE.com"},
+		{name: "other origin", allowed: []string{"https://app.example.com"}, origin: "https://evil.com"},
+		{name: "other scheme", allowed: []string{"https://app.example.com"}, origin: "http://app.example.com"},
+		{name: "wildcard subdomain", allowed: []string{"https://*.example.com"}, origin: "https://app.example.com", want: "https://app.example.com"},
+		{name: "wildcard nested subdomain", allowed: []string{"https://*.example.com"}, origin: "https://a.b.example.com", want: "https://a.b.example.com"},
+		{name: "wildcard needs a subdomain", allowed: []string{"https://*.example.com"}, origin: "https://.example.com"},
+		{name: "wildcard does not match the apex", allowed: []string{"https://*.example.com"}, origin: "https://example.com"},
+		{name: "wildcard suffix must end the origin", allowed: []string{"https://*.example.com"}, origin: "https://app.example.com.evil.com"},
+		{name: "wildcard lookalike domain", allowed: []string{"https://*.example.com"}, origin: "https://app.evilexample.com"},
+		{name: "any origin", allowed: []string{"*"}, origin: "https://anything.test", want: "*"},
+		{name: "nothing allowed", origin: "https://app.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			corsHandler(CORSOptions{AllowedOrigins: tt.allowed, ExposedHeaders: []string{"ETag"}}).ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.want)
+			}
+			if exposed := rec.Header().Get("Access-Control-Expose-Headers"); (exposed != "") != (tt.want != "") {
+				t.Errorf("Access-Control-Expose-Headers = %q with allowed origin %q", exposed, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	opts := CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST", "DELETE"},
+		AllowedHeaders: []string{"Content-Type", "X-Tenant-ID"},
+		MaxAge:         600,
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		origin      string
+		method      string
+		wantStatus  int
+		wantAllowed bool
+	}{
+		{name: "allowed", path: "/api/subscriptions", origin: "https://app.example.com", method: "POST", wantStatus: http.StatusNoContent, wantAllowed: true},
+		{name: "route with a variable", path: "/api/subscriptions/1", origin: "https://app.example.com", method: "DELETE", wantStatus: http.StatusNoContent, wantAllowed: true},
+		{name: "disallowed origin", path: "/api/subscriptions", origin: "https://evil.com", method: "POST", wantStatus: http.StatusNoContent},
+		{name: "method the route does not serve", path: "/api/subscriptions", origin: "https://app.example.com", method: "DELETE", wantStatus: http.StatusMethodNotAllowed},
+		{name: "method not allowed by CORS", path: "/api/subscriptions/1", origin: "https://app.example.com", method: "PUT", wantStatus: http.StatusMethodNotAllowed},
+		{name: "unknown path", path: "/api/unknown", origin: "https://app.example.com", method: "GET", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			rec := httptest.NewRecorder()
+			corsHandler(opts).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			h := rec.Header()
+			if allowed := h.Get("Access-Control-Allow-Origin") == tt.origin; allowed != tt.wantAllowed {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want allowed %v", h.Get("Access-Control-Allow-Origin"), tt.wantAllowed)
+			}
+			if !tt.wantAllowed {
+				return
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != "GET, POST, DELETE" {
+				t.Errorf("Access-Control-Allow-Methods = %q", got)
+			}
+			if got := h.Get("Access-Control-Allow-Headers"); got != "Content-Type, X-Tenant-ID" {
+				t.Errorf("Access-Control-Allow-Headers = %q", got)
+			}
+			if got := h.Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+			}
+		})
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     []string
+		credentials bool
+		wantOrigin  string
+		wantVary    bool
+	}{
+		{name: "any origin without credentials", allowed: []string{"*"}, wantOrigin: "*"},
+		{name: "any origin with credentials echoes the origin", allowed: []string{"*"}, credentials: true, wantOrigin: "https://app.example.com", wantVary: true},
+		{name: "listed origin with credentials", allowed: []string{"https://app.example.com"}, credentials: true, wantOrigin: "https://app.example.com", wantVary: true},
+		{name: "listed origin without credentials", allowed: []string{"https://app.example.com"}, wantOrigin: "https://app.example.com", wantVary: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			rec := httptest.NewRecorder()
+			corsHandler(CORSOptions{AllowedOrigins: tt.allowed, AllowCredentials: tt.credentials}).ServeHTTP(rec, req)
+
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); (got == "true") != tt.credentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want credentials %v", got, tt.credentials)
+			}
+			if vary := h.Get("Vary") == "Origin"; vary != tt.wantVary {
+				t.Errorf("Vary = %q, want Origin %v", h.Get("Vary"), tt.wantVary)
+			}
+		})
+	}
+}
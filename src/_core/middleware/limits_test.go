@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback time.Duration
+		routes   map[string]time.Duration
+		path     string
+		want     time.Duration // 0 means no deadline
+	}{
+		{name: "fallback", fallback: time.Minute, path: "/api/subscriptions", want: time.Minute},
+		{name: "route override", fallback: time.Minute, routes: map[string]time.Duration{"/api/subscriptions/summary": time.Hour}, path: "/api/subscriptions/summary", want: time.Hour},
+		{name: "override for another route", fallback: time.Minute, routes: map[string]time.Duration{"/api/subscriptions/summary": time.Hour}, path: "/api/subscriptions", want: time.Minute},
+		{name: "override by template", fallback: time.Minute, routes: map[string]time.Duration{"/api/subscriptions/{id}": time.Hour}, path: "/api/subscriptions/42", want: time.Hour},
+		{name: "disabled", path: "/api/subscriptions"},
+		{name: "disabled for a route", fallback: time.Minute, routes: map[string]time.Duration{"/api/subscriptions/summary": 0}, path: "/api/subscriptions/summary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadline time.Time
+			var hasDeadline bool
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				deadline, hasDeadline = r.Context().Deadline()
+			}
+
+			r := mux.NewRouter()
+			r.HandleFunc("/api/subscriptions", handler)
+			r.HandleFunc("/api/subscriptions/summary", handler)
+			r.HandleFunc("/api/subscriptions/{id}", handler)
+			r.Use(Timeout(tt.fallback, tt.routes))
+
+			start := time.Now()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			end := time.Now()
+
+			if hasDeadline != (tt.want > 0) {
+				t.Fatalf("deadline set = %v, want %v", hasDeadline, tt.want > 0)
+			}
+			if hasDeadline && (deadline.Before(start.Add(tt.want)) || deadline.After(end.Add(tt.want))) {
+				t.Errorf("timeout = %v, want %v", deadline.Sub(start), tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		body    string
+		wantErr bool
+	}{
+		{name: "under the limit", limit: 10, body: "12345"},
+		{name: "at the limit", limit: 5, body: "12345"},
+		{name: "over the limit", limit: 4, body: "12345", wantErr: true},
+		{name: "no limit", body: strings.Repeat("x", 1<<20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var read []byte
+			var err error
+			h := MaxBytes(tt.limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				read, err = io.ReadAll(r.Body)
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) != tt.wantErr {
+				t.Fatalf("err = %v, want too large %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(read) != tt.body {
+				t.Errorf("read %d bytes, want %d", len(read), len(tt.body))
+			}
+		})
+	}
+}
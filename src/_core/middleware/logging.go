@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"effective_mobile/src/_core/logger"
+	"effective_mobile/src/_core/requestid"
+	"effective_mobile/src/_core/tracing"
+
+	"github.com/google/uuid"
+)
+
+// RequestID reuses a valid X-Request-ID header or generates a new ID, echoes
+// it in the response and stores it in the request context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.WithID(r.Context(), id)))
+	})
+}
+
+// AccessLog attaches a request scoped logger to the context and writes one
+// line per request once it is served. It wraps the whole handler, inside
+// Route, so requests no route serves are logged too; TraceLog adds the trace
+// ID once the tracing middleware has started the span.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		sw := newStatusWriter(w)
+
+		ctx, collected := logger.NewScope(r.Context())
+		ctx = logger.With(ctx, slog.String("request_id", requestid.FromContext(ctx)))
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(sw, r)
+
+		status := sw.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		slog.Default().With(collected()...).Log(ctx, level, "HTTP request",
+			slog.String("method", r.Method),
+			slog.String("route", routeTemplate(r)),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", sw.bytes),
+			slog.Duration("latency", time.Since(started)),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("user_agent", r.UserAgent()),
+		)
+	})
+}
+
+// TraceLog adds the trace ID of the current span to the request scoped
+// logger and so to the access log line. Attach it with Router.Use after the
+// tracing middleware.
+func TraceLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if traceID := tracing.TraceID(r.Context()); traceID != "" {
+			r = r.WithContext(logger.With(r.Context(), slog.String("trace_id", traceID)))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"effective_mobile/src/_core/metrics"
+)
+
+// Metrics counts requests and observes their latency, labelled by the route
+// template rather than the raw path. It wraps the whole handler, inside
+// Route, so requests no route serves are counted as "unmatched".
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		sw := newStatusWriter(w)
+
+		next.ServeHTTP(sw, r)
+
+		route := routeTemplate(r)
+		metrics.HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(sw.Status())).Inc()
+		metrics.HTTPDuration.WithLabelValues(r.Method, route).Observe(time.Since(started).Seconds())
+	})
+}
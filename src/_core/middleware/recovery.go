@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"effective_mobile/src/_core/logger"
+	"effective_mobile/src/_core/response"
+)
+
+// Recover turns a panic in a handler into a 500 response and logs it with
+// the stack trace instead of dropping the connection.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := newStatusWriter(w)
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			logger.FromContext(r.Context()).ErrorContext(r.Context(), "Panic recovered",
+				slog.String("panic", fmt.Sprint(recovered)),
+				slog.String("stack", string(debug.Stack())),
+			)
+
+			if sw.status == 0 {
+				response.Error(sw, r, http.StatusInternalServerError, "Internal server error")
+			}
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
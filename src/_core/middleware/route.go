@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type routeKey struct{}
+
+// Route matches the request against router before it is served, so
+// middleware wrapped around the router can label requests by the route
+// template. Requests no route serves, like 404s, 405s and CORS preflights,
+// are labelled "unmatched".
+func Route(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			template := "unmatched"
+			var match mux.RouteMatch
+			if router.Match(r, &match) && match.Route != nil {
+				template = templateOf(match.Route)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, template)))
+		})
+	}
+}
+
+func routeTemplate(r *http.Request) string {
+	if template, ok := r.Context().Value(routeKey{}).(string); ok {
+		return template
+	}
+	if route := mux.CurrentRoute(r); route != nil {
+		return templateOf(route)
+	}
+	return "unmatched"
+}
+
+func templateOf(route *mux.Route) string {
+	if template, err := route.GetPathTemplate(); err == nil {
+		return template
+	}
+	if prefix, err := route.GetPathRegexp(); err == nil {
+		return prefix
+	}
+	return "unknown"
+}
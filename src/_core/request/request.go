@@ -0,0 +1,50 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var ErrTrailingData = errors.New("request body must contain a single JSON value")
+
+// DecodeJSON decodes the request body into dst, rejecting unknown fields and
+// anything after the first JSON value.
+func DecodeJSON(r *http.Request, dst any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return err
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		// The body limit can be hit while looking past the first value.
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return err
+		}
+		return ErrTrailingData
+	}
+
+	return nil
+}
+
+// StatusCode maps a DecodeJSON error to the HTTP status to answer with.
+func StatusCode(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// Message describes a DecodeJSON error for the client.
+func Message(err error) string {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit)
+	}
+	return err.Error()
+}
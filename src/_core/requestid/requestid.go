@@ -0,0 +1,26 @@
+package requestid
+
+import (
+	"context"
+	"regexp"
+)
+
+const Header = "X-Request-ID"
+
+// Incoming IDs are reused only if they are short and printable.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type ctxKey struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
@@ -0,0 +1,40 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"effective_mobile/src/_core/tracing"
+)
+
+// ErrorResponse represents API error response
+// swagger:model ErrorResponse
+type ErrorResponse struct {
+	// Error message
+	Message string `json:"message"`
+
+	// Optional list of detailed errors
+	Errors []string `json:"errors,omitempty"`
+
+	// HTTP status code
+	StatusCode int `json:"status_code"`
+
+	// ID of the request trace, to look the failure up in the tracing backend
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+func Error(w http.ResponseWriter, r *http.Request, statusCode int, message string, details ...string) {
+	response := ErrorResponse{
+		Message:    message,
+		Errors:     details,
+		StatusCode: statusCode,
+		TraceID:    tracing.TraceID(r.Context()),
+	}
+	JSON(w, statusCode, response)
+}
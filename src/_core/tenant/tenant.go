@@ -0,0 +1,80 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"effective_mobile/src/_core/logger"
+	"effective_mobile/src/_core/response"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const Header = "X-Tenant-ID"
+
+var ErrMissing = errors.New("tenant is not resolved")
+
+type ctxKey struct{}
+
+func WithID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(ctxKey{}).(uuid.UUID)
+	return id, ok
+}
+
+// Middleware resolves the tenant of the request. A tenant already placed in
+// the context by an earlier middleware wins; this service has no
+// authentication of its own, so none does unless one is added in front of
+// it. Otherwise the X-Tenant-ID header names the tenant, but only when
+// trustHeader is set: whoever sends the header picks the tenant, so it must
+// only be trusted behind a gateway that sets it from verified credentials.
+// fallback is used when the header is absent (single-tenant deployments).
+func Middleware(fallback *uuid.UUID, trustHeader bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := FromContext(r.Context())
+			if !ok {
+				value := r.Header.Get(Header)
+				switch {
+				case value != "" && !trustHeader:
+					response.Error(w, r, http.StatusBadRequest, "Tenant header is not trusted", Header+" is not accepted by this deployment")
+					return
+				case value != "":
+					parsed, err := uuid.Parse(value)
+					if err != nil {
+						response.Error(w, r, http.StatusBadRequest, "Invalid tenant ID", err.Error())
+						return
+					}
+					id = parsed
+				case fallback != nil:
+					id = *fallback
+				default:
+					response.Error(w, r, http.StatusBadRequest, "Tenant is required", "missing "+Header+" header")
+					return
+				}
+			}
+
+			ctx := logger.With(WithID(r.Context(), id), slog.String("tenant_id", id.String()))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Scope restricts a query to the tenant stored in ctx. Queries without a
+// resolved tenant fail instead of silently reading every tenant's rows.
+func Scope(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		id, ok := FromContext(ctx)
+		if !ok {
+			db.AddError(ErrMissing)
+			return db
+		}
+		return db.Where("tenant_id = ?", id)
+	}
+}
@@ -0,0 +1,59 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	fallback := uuid.MustParse("00000000-0000-4000-8000-000000000001")
+	header := uuid.MustParse("00000000-0000-4000-8000-000000000002")
+	placed := uuid.MustParse("00000000-0000-4000-8000-000000000003")
+
+	tests := []struct {
+		name        string
+		fallback    *uuid.UUID
+		trustHeader bool
+		header      string
+		placed      *uuid.UUID
+		wantStatus  int
+		wantTenant  uuid.UUID
+	}{
+		{name: "fallback without a header", fallback: &fallback, wantStatus: http.StatusOK, wantTenant: fallback},
+		{name: "trusted header", fallback: &fallback, trustHeader: true, header: header.String(), wantStatus: http.StatusOK, wantTenant: header},
+		{name: "untrusted header", fallback: &fallback, header: header.String(), wantStatus: http.StatusBadRequest},
+		{name: "invalid header", trustHeader: true, header: "tenant-1", wantStatus: http.StatusBadRequest},
+		{name: "no tenant", trustHeader: true, wantStatus: http.StatusBadRequest},
+		{name: "tenant in the context wins", fallback: &fallback, header: header.String(), placed: &placed, wantStatus: http.StatusOK, wantTenant: placed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got uuid.UUID
+			handler := Middleware(tt.fallback, tt.trustHeader)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/subscriptions", nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			if tt.placed != nil {
+				req = req.WithContext(WithID(context.Background(), *tt.placed))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got != tt.wantTenant {
+				t.Errorf("tenant = %v, want %v", got, tt.wantTenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditEvent records one change made through a service. Changes holds a JSON
+// object mapping each changed field to {"from": ..., "to": ...}.
+// SubscriptionID is the subscription the entity is or belongs to, so its
+// history includes changes to scheduled changes, discounts and members.
+// Actor is verified; ActorClaimed is who the request said it acted for.
+type AuditEvent struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID       uuid.UUID  `gorm:"type:uuid;not null" json:"tenant_id"`
+	EntityType     string     `gorm:"size:50;not null" json:"entity_type"`
+	EntityID       uuid.UUID  `gorm:"type:uuid;not null" json:"entity_id"`
+	SubscriptionID *uuid.UUID `gorm:"type:uuid" json:"subscription_id,omitempty"`
+	Action         string     `gorm:"size:20;not null" json:"action"`
+	Actor          string     `gorm:"size:100;not null" json:"actor"`
+	ActorClaimed   string     `gorm:"size:100" json:"actor_claimed,omitempty"`
+	RequestID      string     `gorm:"size:128" json:"request_id"`
+	Changes        string     `gorm:"type:text;not null" json:"changes"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (e *AuditEvent) BeforeCreate(*gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubscriptionChange is a price or plan change scheduled for the month of
+// EffectiveFrom. Nil fields are left unchanged. A change is pending until
+// AppliedAt or CancelledAt is set; neither is ever cleared.
+type SubscriptionChange struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	TenantID       uuid.UUID  `gorm:"type:uuid;not null" json:"tenant_id"`
+	EffectiveFrom  time.Time  `gorm:"not null" json:"effective_from"`
+	Price          *float64   `gorm:"type:numeric(10,2)" json:"price,omitempty"`
+	ServiceName    *string    `gorm:"size:100" json:"service_name,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	AppliedAt      *time.Time `json:"applied_at,omitempty"`
+	CancelledAt    *time.Time `json:"cancelled_at,omitempty"`
+}
+
+// PendingAt reports whether the change was scheduled and neither applied nor
+// cancelled at t.
+func (c *SubscriptionChange) PendingAt(t time.Time) bool {
+	return !c.CreatedAt.After(t) &&
+		(c.AppliedAt == nil || c.AppliedAt.After(t)) &&
+		(c.CancelledAt == nil || c.CancelledAt.After(t))
+}
+
+// Pending reports whether the change is still waiting to be applied.
+func (c *SubscriptionChange) Pending() bool {
+	return c.AppliedAt == nil && c.CancelledAt == nil
+}
+
+func (c *SubscriptionChange) BeforeCreate(*gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
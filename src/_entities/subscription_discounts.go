@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Discount kinds: a percentage of the monthly price, or a fixed amount off
+// it.
+const (
+	DiscountPercent = "percent"
+	DiscountFixed   = "fixed"
+)
+
+// SubscriptionDiscount lowers the price of a subscription from the month of
+// StartDate, either through EndDate or for the first Cycles billed months;
+// with neither set it lasts as long as the subscription. Removing a
+// discount sets ValidTo, like replacing a SubscriptionPrice entry.
+type SubscriptionDiscount struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	TenantID       uuid.UUID  `gorm:"type:uuid;not null" json:"tenant_id"`
+	Kind           string     `gorm:"size:10;not null" json:"kind"`
+	Amount         float64    `gorm:"type:numeric(10,2);not null" json:"amount"`
+	StartDate      time.Time  `gorm:"not null" json:"start_date"`
+	EndDate        *time.Time `json:"end_date,omitempty"`
+	Cycles         *int       `json:"cycles,omitempty"`
+	Description    *string    `gorm:"size:200" json:"description,omitempty"`
+	ValidFrom      time.Time  `gorm:"not null" json:"valid_from"`
+	ValidTo        *time.Time `json:"valid_to,omitempty"`
+}
+
+// ValidAt reports whether the discount was added and not yet removed at t.
+func (d *SubscriptionDiscount) ValidAt(t time.Time) bool {
+	return !d.ValidFrom.After(t) && (d.ValidTo == nil || d.ValidTo.After(t))
+}
+
+// Off returns the amount taken off price in a month the discount covers.
+func (d *SubscriptionDiscount) Off(price float64) float64 {
+	if d.Kind == DiscountPercent {
+		return price * d.Amount / 100
+	}
+	return d.Amount
+}
+
+func (d *SubscriptionDiscount) BeforeCreate(*gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Split rules of a shared subscription: an equal part of what is left, a
+// percentage of the monthly cost, or a fixed monthly amount.
+const (
+	SplitEqual   = "equal"
+	SplitPercent = "percent"
+	SplitFixed   = "fixed"
+)
+
+// SubscriptionMember is a user sharing a subscription paid by its owner, the
+// subscription's UserID. Share is the percentage or amount of the percent
+// and fixed rules; equal members and the owner split the rest. Changing or
+// removing a member sets ValidTo, like removing a SubscriptionDiscount.
+type SubscriptionMember struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	TenantID       uuid.UUID  `gorm:"type:uuid;not null" json:"tenant_id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
+	Split          string     `gorm:"size:10;not null" json:"split"`
+	Share          *float64   `gorm:"type:numeric(10,2)" json:"share,omitempty"`
+	ValidFrom      time.Time  `gorm:"not null" json:"valid_from"`
+	ValidTo        *time.Time `json:"valid_to,omitempty"`
+}
+
+// ValidAt reports whether the entry was recorded and not yet replaced at t.
+func (m *SubscriptionMember) ValidAt(t time.Time) bool {
+	return !m.ValidFrom.After(t) && (m.ValidTo == nil || m.ValidTo.After(t))
+}
+
+func (m *SubscriptionMember) BeforeCreate(*gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubscriptionPrice is the monthly price of a subscription from the month of
+// EffectiveFrom until the next entry. The first entry also covers any months
+// before it. Like SubscriptionVersion, entries are never edited: a
+// replaced entry gets ValidTo set, so past summaries can be reproduced.
+type SubscriptionPrice struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	TenantID       uuid.UUID  `gorm:"type:uuid;not null" json:"tenant_id"`
+	Price          float64    `gorm:"type:numeric(10,2);not null" json:"price"`
+	EffectiveFrom  time.Time  `gorm:"not null" json:"effective_from"`
+	ValidFrom      time.Time  `gorm:"not null" json:"valid_from"`
+	ValidTo        *time.Time `json:"valid_to,omitempty"`
+}
+
+// ValidAt reports whether the entry was recorded and not yet replaced at t.
+func (p *SubscriptionPrice) ValidAt(t time.Time) bool {
+	return !p.ValidFrom.After(t) && (p.ValidTo == nil || p.ValidTo.After(t))
+}
+
+func (p *SubscriptionPrice) BeforeCreate(*gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
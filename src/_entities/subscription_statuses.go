@@ -0,0 +1,42 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Lifecycle statuses of a subscription. Only active months are billed.
+const (
+	StatusTrial     = "trial"
+	StatusActive    = "active"
+	StatusPaused    = "paused"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// SubscriptionStatus is the lifecycle status of a subscription from the
+// month of EffectiveFrom until the next entry. Entries are kept like
+// SubscriptionPrice ones: replaced entries get ValidTo set.
+type SubscriptionStatus struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	TenantID       uuid.UUID  `gorm:"type:uuid;not null" json:"tenant_id"`
+	Status         string     `gorm:"size:20;not null" json:"status"`
+	EffectiveFrom  time.Time  `gorm:"not null" json:"effective_from"`
+	ValidFrom      time.Time  `gorm:"not null" json:"valid_from"`
+	ValidTo        *time.Time `json:"valid_to,omitempty"`
+}
+
+// ValidAt reports whether the entry was recorded and not yet replaced at t.
+func (s *SubscriptionStatus) ValidAt(t time.Time) bool {
+	return !s.ValidFrom.After(t) && (s.ValidTo == nil || s.ValidTo.After(t))
+}
+
+func (s *SubscriptionStatus) BeforeCreate(*gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
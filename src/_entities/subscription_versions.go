@@ -0,0 +1,94 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubscriptionVersion is the state of a subscription between ValidFrom and
+// ValidTo. The current version has no ValidTo; a deleted subscription has
+// none open until it is restored.
+type SubscriptionVersion struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	TenantID       uuid.UUID  `gorm:"type:uuid;not null" json:"tenant_id"`
+	ServiceName    string     `gorm:"size:100;not null" json:"service_name"`
+	Price          float64    `gorm:"type:numeric(10,2);not null" json:"price"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
+	StartDate      time.Time  `gorm:"not null" json:"start_date"`
+	EndDate        *time.Time `json:"end_date,omitempty"`
+	CreatedAt      time.Time  `gorm:"not null" json:"created_at"`
+	ValidFrom      time.Time  `gorm:"not null" json:"valid_from"`
+	ValidTo        *time.Time `json:"valid_to,omitempty"`
+
+	Status             string     `gorm:"size:20;not null" json:"status"`
+	CancelAt           *time.Time `json:"cancel_at,omitempty"`
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+	CancellationReason *string    `gorm:"size:500" json:"cancellation_reason,omitempty"`
+
+	TrialStart *time.Time `json:"trial_start,omitempty"`
+	TrialEnd   *time.Time `json:"trial_end,omitempty"`
+	TrialPrice *float64   `gorm:"type:numeric(10,2)" json:"trial_price,omitempty"`
+}
+
+// NewSubscriptionVersion snapshots sub as valid from the given time.
+func NewSubscriptionVersion(sub *Subscriptions, from time.Time) SubscriptionVersion {
+	return SubscriptionVersion{
+		SubscriptionID: sub.ID,
+		TenantID:       sub.TenantID,
+		ServiceName:    sub.ServiceName,
+		Price:          sub.Price,
+		UserID:         sub.UserID,
+		StartDate:      sub.StartDate,
+		EndDate:        sub.EndDate,
+		CreatedAt:      sub.CreatedAt,
+		ValidFrom:      from,
+
+		Status:             sub.Status,
+		CancelAt:           sub.CancelAt,
+		CancelledAt:        sub.CancelledAt,
+		CancellationReason: sub.CancellationReason,
+
+		TrialStart: sub.TrialStart,
+		TrialEnd:   sub.TrialEnd,
+		TrialPrice: sub.TrialPrice,
+	}
+}
+
+// Subscription returns the subscription as it was in this version.
+func (v *SubscriptionVersion) Subscription() Subscriptions {
+	return Subscriptions{
+		ID:          v.SubscriptionID,
+		TenantID:    v.TenantID,
+		ServiceName: v.ServiceName,
+		Price:       v.Price,
+		UserID:      v.UserID,
+		StartDate:   v.StartDate,
+		EndDate:     v.EndDate,
+		CreatedAt:   v.CreatedAt,
+		UpdatedAt:   v.ValidFrom,
+
+		Status:             v.Status,
+		CancelAt:           v.CancelAt,
+		CancelledAt:        v.CancelledAt,
+		CancellationReason: v.CancellationReason,
+
+		TrialStart: v.TrialStart,
+		TrialEnd:   v.TrialEnd,
+		TrialPrice: v.TrialPrice,
+	}
+}
+
+// ValidAt reports whether the version was current at t.
+func (v *SubscriptionVersion) ValidAt(t time.Time) bool {
+	return !v.ValidFrom.After(t) && (v.ValidTo == nil || v.ValidTo.After(t))
+}
+
+func (v *SubscriptionVersion) BeforeCreate(*gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
@@ -4,15 +4,42 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Subscriptions struct {
-	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	ServiceName string     `gorm:"size:100;not null" json:"service_name"`
-	Price       float64    `gorm:"type:numeric(10,2);not null" json:"price"`
-	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
-	StartDate   time.Time  `gorm:"not null" json:"start_date"`
-	EndDate     *time.Time `gorm:"index" json:"end_date,omitempty"`
-	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	ID          uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	TenantID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	ServiceName string         `gorm:"size:100;not null" json:"service_name"`
+	Price       float64        `gorm:"type:numeric(10,2);not null" json:"price"`
+	UserID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	StartDate   time.Time      `gorm:"not null" json:"start_date"`
+	EndDate     *time.Time     `gorm:"index" json:"end_date,omitempty"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	// Status is the current lifecycle status, see StatusActive. CancelAt is
+	// the first month no longer billed once a cancellation is requested;
+	// until then a cancellation at period end leaves Status unchanged.
+	Status             string     `gorm:"size:20;not null;default:active" json:"status"`
+	CancelAt           *time.Time `json:"cancel_at,omitempty"`
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+	CancellationReason *string    `gorm:"size:500" json:"cancellation_reason,omitempty"`
+
+	// TrialStart and TrialEnd are the first and last months of the trial,
+	// billed at TrialPrice (free when unset). The subscription converts to
+	// active after TrialEnd, or is cancelled if CancelAt was set for it.
+	TrialStart *time.Time `json:"trial_start,omitempty"`
+	TrialEnd   *time.Time `json:"trial_end,omitempty"`
+	TrialPrice *float64   `gorm:"type:numeric(10,2)" json:"trial_price,omitempty"`
+}
+
+// BeforeCreate assigns the ID in Go so inserts work the same on every
+// dialect, including SQLite which has no UUID generator.
+func (s *Subscriptions) BeforeCreate(*gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
 }
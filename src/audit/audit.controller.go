@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"net/http"
+
+	"effective_mobile/src/_core/response"
+	"effective_mobile/src/_core/validator"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Entity types of the recorded events. Member events are keyed by the
+// member's user ID, since every change to a member stores a new row.
+const (
+	EntitySubscription         = "subscription"
+	EntitySubscriptionChange   = "subscription_change"
+	EntitySubscriptionDiscount = "subscription_discount"
+	EntitySubscriptionMember   = "subscription_member"
+)
+
+type AuditController struct {
+	service *AuditService
+}
+
+func NewAuditController(service *AuditService) *AuditController {
+	return &AuditController{service: service}
+}
+
+// RegisterRoutes mounts the audit endpoints; admin guards the tenant-wide
+// audit log.
+func (c *AuditController) RegisterRoutes(r *mux.Router, admin func(http.Handler) http.Handler) {
+	validator.Init()
+
+	r.HandleFunc("/subscriptions/{id}/history", c.SubscriptionHistory).Methods("GET")
+	r.Handle("/audit", admin(http.HandlerFunc(c.List))).Methods("GET")
+}
+
+// SubscriptionHistory godoc
+// @Summary Get subscription history
+// @Description Returns every recorded change of a subscription and of its scheduled changes, discounts and members, newest first
+// @Tags Audit
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Param request query AuditList false "Filtering parameters"
+// @Success 200 {array} ResAuditEvent
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/history [get]
+func (c *AuditController) SubscriptionHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	filter := listFromQuery(r)
+	filter.SubscriptionID = id.String()
+
+	c.list(w, r, filter)
+}
+
+// List godoc
+// @Summary List audit events
+// @Description Returns the tenant's audit log, newest first. Requires the admin token.
+// @Tags Audit
+// @Produce json
+// @Security BearerAuth
+// @Param request query AuditList false "Filtering parameters"
+// @Success 200 {array} ResAuditEvent
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /audit [get]
+func (c *AuditController) List(w http.ResponseWriter, r *http.Request) {
+	c.list(w, r, listFromQuery(r))
+}
+
+func (c *AuditController) list(w http.ResponseWriter, r *http.Request, filter AuditList) {
+	if err := validator.Validate.Struct(filter); err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	options, err := filter.Options()
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid filter", err.Error())
+		return
+	}
+
+	events, err := c.service.List(r.Context(), options)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, "Failed to list audit events", err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, events)
+}
+
+func listFromQuery(r *http.Request) AuditList {
+	query := r.URL.Query()
+	return AuditList{
+		EntityType:     query.Get("entity_type"),
+		EntityID:       query.Get("entity_id"),
+		SubscriptionID: query.Get("subscription_id"),
+		Actor:          query.Get("actor"),
+		ActorClaimed:   query.Get("actor_claimed"),
+		Action:         query.Get("action"),
+		From:           query.Get("from"),
+		To:             query.Get("to"),
+		Limit:          query.Get("limit"),
+		Offset:         query.Get("offset"),
+	}
+}
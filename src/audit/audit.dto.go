@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Change
+// swagger:model AuditChange
+type Change struct {
+	// Value before the change, null when the entity did not exist
+	From any `json:"from"`
+
+	// Value after the change, null when the entity was deleted
+	To any `json:"to"`
+}
+
+// ResAuditEvent
+// swagger:model AuditEvent
+type ResAuditEvent struct {
+	// Unique identifier of the event
+	ID uuid.UUID `json:"id"`
+
+	// Kind of entity that changed, e.g. subscription
+	EntityType string `json:"entity_type"`
+
+	// Identifier of the entity that changed
+	EntityID uuid.UUID `json:"entity_id"`
+
+	// Subscription the entity is or belongs to
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty"`
+
+	// create, update, delete or restore
+	Action string `json:"action"`
+
+	// Who made the change, as verified by the service (anonymous when unknown)
+	Actor string `json:"actor"`
+
+	// Who the request said made the change (X-Actor header, not verified)
+	ActorClaimed string `json:"actor_claimed,omitempty"`
+
+	// Request that made the change
+	RequestID string `json:"request_id,omitempty"`
+
+	// Changed fields with their old and new values
+	Changes map[string]Change `json:"changes"`
+
+	// When the change was made
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditList contains filtering parameters
+// swagger:parameters auditList
+type AuditList struct {
+	// Entity type to filter by, e.g. subscription
+	EntityType string `json:"entity_type" validate:"omitempty,max=50"`
+
+	// Entity ID to filter by
+	EntityID string `json:"entity_id" validate:"omitempty,uuid"`
+
+	// Subscription ID to filter by, including its scheduled changes, discounts and members
+	SubscriptionID string `json:"subscription_id" validate:"omitempty,uuid"`
+
+	// Actor to filter by
+	Actor string `json:"actor" validate:"omitempty,max=100"`
+
+	// Claimed actor (X-Actor header) to filter by
+	ActorClaimed string `json:"actor_claimed" validate:"omitempty,max=100"`
+
+	// Action to filter by
+	Action string `json:"action" validate:"omitempty,oneof=create update delete restore"`
+
+	// Only events at or after this time (RFC 3339)
+	From string `json:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+
+	// Only events before this time (RFC 3339)
+	To string `json:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+
+	// Maximum number of results to return (1-500, default 50)
+	Limit string `json:"limit" validate:"omitempty,number"`
+
+	// Offset for pagination
+	Offset string `json:"offset" validate:"omitempty,number"`
+}
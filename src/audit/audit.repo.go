@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"effective_mobile/src/_core/db"
+	"effective_mobile/src/_core/tenant"
+	entities "effective_mobile/src/_entities"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditRepository stores audit events. Both methods are scoped to the tenant
+// in ctx; Create joins the transaction in ctx, if any.
+type AuditRepository interface {
+	Create(ctx context.Context, event *entities.AuditEvent) error
+	List(ctx context.Context, filter ListOptions) ([]entities.AuditEvent, error)
+}
+
+var _ AuditRepository = (*AuditRepo)(nil)
+
+type AuditRepo struct {
+	cluster *db.Cluster
+}
+
+func NewAuditRepo(cluster *db.Cluster) *AuditRepo {
+	return &AuditRepo{cluster: cluster}
+}
+
+func (r *AuditRepo) Create(ctx context.Context, event *entities.AuditEvent) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+	event.TenantID = tenantID
+
+	if err := r.cluster.Writer(ctx).Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditRepo) List(ctx context.Context, filter ListOptions) ([]entities.AuditEvent, error) {
+	var events []entities.AuditEvent
+
+	tenantID, _ := tenant.FromContext(ctx)
+	err := r.cluster.Reader(ctx, tenantID.String()).WithContext(ctx).
+		Model(&entities.AuditEvent{}).
+		Scopes(tenant.Scope(ctx), filter.scope).
+		Order("created_at DESC").
+		Find(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// ListOptions filters audit events; zero values match everything.
+type ListOptions struct {
+	EntityType     string
+	EntityID       *uuid.UUID
+	SubscriptionID *uuid.UUID
+	Actor          string
+	ActorClaimed   string
+	Action         string
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+	Offset         int
+}
+
+func (o ListOptions) scope(query *gorm.DB) *gorm.DB {
+	if o.EntityType != "" {
+		query = query.Where("entity_type = ?", o.EntityType)
+	}
+	if o.EntityID != nil {
+		query = query.Where("entity_id = ?", o.EntityID)
+	}
+	if o.SubscriptionID != nil {
+		query = query.Where("subscription_id = ?", o.SubscriptionID)
+	}
+	if o.Actor != "" {
+		query = query.Where("actor = ?", o.Actor)
+	}
+	if o.ActorClaimed != "" {
+		query = query.Where("actor_claimed = ?", o.ActorClaimed)
+	}
+	if o.Action != "" {
+		query = query.Where("action = ?", o.Action)
+	}
+	if o.From != nil {
+		query = query.Where("created_at >= ?", o.From)
+	}
+	if o.To != nil {
+		query = query.Where("created_at < ?", o.To)
+	}
+	return query.Limit(o.Limit).Offset(o.Offset)
+}
+
+// match mirrors scope for rows filtered in memory, without pagination.
+func (o ListOptions) match(event *entities.AuditEvent) bool {
+	return (o.EntityType == "" || event.EntityType == o.EntityType) &&
+		(o.EntityID == nil || event.EntityID == *o.EntityID) &&
+		(o.SubscriptionID == nil || (event.SubscriptionID != nil && *event.SubscriptionID == *o.SubscriptionID)) &&
+		(o.Actor == "" || event.Actor == o.Actor) &&
+		(o.ActorClaimed == "" || event.ActorClaimed == o.ActorClaimed) &&
+		(o.Action == "" || event.Action == o.Action) &&
+		(o.From == nil || !event.CreatedAt.Before(*o.From)) &&
+		(o.To == nil || event.CreatedAt.Before(*o.To))
+}
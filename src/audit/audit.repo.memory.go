@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"effective_mobile/src/_core/tenant"
+	entities "effective_mobile/src/_entities"
+
+	"github.com/google/uuid"
+)
+
+var _ AuditRepository = (*MemoryAuditRepo)(nil)
+
+// MemoryAuditRepo keeps audit events in process memory next to
+// subscriptions.MemorySubscriptionRepo.
+type MemoryAuditRepo struct {
+	mu     sync.RWMutex
+	events []entities.AuditEvent
+}
+
+func NewMemoryAuditRepo() *MemoryAuditRepo {
+	return &MemoryAuditRepo{}
+}
+
+func (r *MemoryAuditRepo) Create(ctx context.Context, event *entities.AuditEvent) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+	event.TenantID = tenantID
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	event.CreatedAt = time.Now().UTC()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, *event)
+
+	return nil
+}
+
+func (r *MemoryAuditRepo) List(ctx context.Context, filter ListOptions) ([]entities.AuditEvent, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Events are appended in time order; walk backwards for newest first.
+	var events []entities.AuditEvent
+	skipped := 0
+	for i := len(r.events) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		event := r.events[i]
+		if event.TenantID != tenantID || !filter.match(&event) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,227 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"effective_mobile/src/_core/actor"
+	"effective_mobile/src/_core/requestid"
+	"effective_mobile/src/_core/tracing"
+	entities "effective_mobile/src/_entities"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+)
+
+// Bookkeeping fields are not reported as changes.
+var ignoredFields = []string{"id", "tenant_id", "created_at", "updated_at", "deleted_at"}
+
+type AuditService struct {
+	repo AuditRepository
+}
+
+func NewAuditService(repo AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record stores who changed entityID from before to after, where nil stands
+// for "did not exist". Call it with the ctx of the transaction making the
+// change so both are committed together.
+func (s *AuditService) Record(ctx context.Context, entityType, action string, entityID uuid.UUID, before, after any) error {
+	var subscriptionID *uuid.UUID
+	if entityType == EntitySubscription {
+		subscriptionID = &entityID
+	}
+	return s.record(ctx, subscriptionID, entityType, action, entityID, before, after)
+}
+
+// RecordChild is Record for an entity that belongs to subscriptionID, so the
+// event shows up in the history of that subscription.
+func (s *AuditService) RecordChild(ctx context.Context, subscriptionID uuid.UUID, entityType, action string, entityID uuid.UUID, before, after any) error {
+	return s.record(ctx, &subscriptionID, entityType, action, entityID, before, after)
+}
+
+func (s *AuditService) record(ctx context.Context, subscriptionID *uuid.UUID, entityType, action string, entityID uuid.UUID, before, after any) (err error) {
+	ctx, span := tracing.Start(ctx, "AuditService.Record",
+		attribute.String("audit.entity_type", entityType),
+		attribute.String("audit.action", action),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	changes, err := diff(before, after)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %w", err)
+	}
+
+	return s.repo.Create(ctx, &entities.AuditEvent{
+		EntityType:     entityType,
+		EntityID:       entityID,
+		SubscriptionID: subscriptionID,
+		Action:         action,
+		Actor:          actor.FromContext(ctx),
+		ActorClaimed:   actor.ClaimFromContext(ctx),
+		RequestID:      requestid.FromContext(ctx),
+		Changes:        string(data),
+	})
+}
+
+// List returns audit events matching filter, newest first.
+func (s *AuditService) List(ctx context.Context, filter ListOptions) (_ []ResAuditEvent, err error) {
+	ctx, span := tracing.Start(ctx, "AuditService.List")
+	defer func() { tracing.End(span, err) }()
+
+	events, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ResAuditEvent, len(events))
+	for i, event := range events {
+		res, err := convertToResponse(&event)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = *res
+	}
+
+	return result, nil
+}
+
+// Options validates the query parameters of an audit listing.
+func (l AuditList) Options() (ListOptions, error) {
+	options := ListOptions{
+		EntityType:   l.EntityType,
+		Actor:        l.Actor,
+		ActorClaimed: l.ActorClaimed,
+		Action:       l.Action,
+		Limit:        50,
+	}
+
+	if l.EntityID != "" {
+		id, err := uuid.Parse(l.EntityID)
+		if err != nil {
+			return options, fmt.Errorf("invalid entity ID")
+		}
+		options.EntityID = &id
+	}
+	if l.SubscriptionID != "" {
+		id, err := uuid.Parse(l.SubscriptionID)
+		if err != nil {
+			return options, fmt.Errorf("invalid subscription ID")
+		}
+		options.SubscriptionID = &id
+	}
+
+	for _, bound := range []struct {
+		value string
+		dst   **time.Time
+	}{{l.From, &options.From}, {l.To, &options.To}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return options, fmt.Errorf("invalid time %q", bound.value)
+		}
+		t = t.UTC()
+		*bound.dst = &t
+	}
+
+	if l.Limit != "" {
+		limit, err := strconv.Atoi(l.Limit)
+		if err != nil || limit < 1 || limit > 500 {
+			return options, fmt.Errorf("limit must be between 1 and 500")
+		}
+		options.Limit = limit
+	}
+
+	if l.Offset != "" {
+		offset, err := strconv.Atoi(l.Offset)
+		if err != nil || offset < 0 {
+			return options, fmt.Errorf("offset must be greater than or equal to 0")
+		}
+		options.Offset = offset
+	}
+
+	return options, nil
+}
+
+func convertToResponse(event *entities.AuditEvent) (*ResAuditEvent, error) {
+	var changes map[string]Change
+	if err := json.Unmarshal([]byte(event.Changes), &changes); err != nil {
+		return nil, fmt.Errorf("failed to decode audit changes of %s: %w", event.ID, err)
+	}
+
+	return &ResAuditEvent{
+		ID:             event.ID,
+		EntityType:     event.EntityType,
+		EntityID:       event.EntityID,
+		SubscriptionID: event.SubscriptionID,
+		Action:         event.Action,
+		Actor:          event.Actor,
+		ActorClaimed:   event.ActorClaimed,
+		RequestID:      event.RequestID,
+		Changes:        changes,
+		CreatedAt:      event.CreatedAt,
+	}, nil
+}
+
+// diff compares the JSON forms of before and after field by field.
+func diff(before, after any) (map[string]Change, error) {
+	from, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	for name, value := range from {
+		if !reflect.DeepEqual(value, to[name]) {
+			changes[name] = Change{From: value, To: to[name]}
+		}
+	}
+	for name, value := range to {
+		if _, ok := from[name]; !ok && value != nil {
+			changes[name] = Change{To: value}
+		}
+	}
+	for _, name := range ignoredFields {
+		delete(changes, name)
+	}
+
+	return changes, nil
+}
+
+func fields(v any) (map[string]any, error) {
+	if rv := reflect.ValueOf(v); !rv.IsValid() || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode audit snapshot: %w", err)
+	}
+	return out, nil
+}
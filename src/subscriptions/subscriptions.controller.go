@@ -1,13 +1,18 @@
 package subscriptions
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"net/http"
+	"time"
 
+	"effective_mobile/src/_core/request"
+	"effective_mobile/src/_core/response"
 	"effective_mobile/src/_core/validator"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 type SubscriptionController struct {
@@ -22,8 +27,24 @@ func (c *SubscriptionController) RegisterRoutes(r *mux.Router) {
 	validator.Init()
 
 	r.HandleFunc("/subscriptions/summary", c.GetSubscriptionSummary).Methods("GET")
+	r.HandleFunc("/subscriptions/trash", c.Trash).Methods("GET")
 	r.HandleFunc("/subscriptions", c.Create).Methods("POST")
 	r.HandleFunc("/subscriptions/{id}", c.GetByID).Methods("GET")
+	r.HandleFunc("/subscriptions/{id}/restore", c.Restore).Methods("POST")
+	r.HandleFunc("/subscriptions/{id}/pause", c.Pause).Methods("POST")
+	r.HandleFunc("/subscriptions/{id}/resume", c.Resume).Methods("POST")
+	r.HandleFunc("/subscriptions/{id}/activate", c.Activate).Methods("POST")
+	r.HandleFunc("/subscriptions/{id}/cancel", c.Cancel).Methods("POST")
+	r.HandleFunc("/subscriptions/{id}/members", c.ListMembers).Methods("GET")
+	r.HandleFunc("/subscriptions/{id}/members", c.AddMember).Methods("POST")
+	r.HandleFunc("/subscriptions/{id}/members/{userId}", c.UpdateMember).Methods("PUT")
+	r.HandleFunc("/subscriptions/{id}/members/{userId}", c.RemoveMember).Methods("DELETE")
+	r.HandleFunc("/subscriptions/{id}/discounts", c.ListDiscounts).Methods("GET")
+	r.HandleFunc("/subscriptions/{id}/discounts", c.AddDiscount).Methods("POST")
+	r.HandleFunc("/subscriptions/{id}/discounts/{discountId}", c.RemoveDiscount).Methods("DELETE")
+	r.HandleFunc("/subscriptions/{id}/changes", c.ListChanges).Methods("GET")
+	r.HandleFunc("/subscriptions/{id}/changes", c.ScheduleChange).Methods("POST")
+	r.HandleFunc("/subscriptions/{id}/changes/{changeId}", c.CancelChange).Methods("DELETE")
 	r.HandleFunc("/subscriptions/{id}", c.Update).Methods("PUT")
 	r.HandleFunc("/subscriptions/{id}", c.Delete).Methods("DELETE")
 	r.HandleFunc("/subscriptions", c.List).Methods("GET")
@@ -37,28 +58,33 @@ func (c *SubscriptionController) RegisterRoutes(r *mux.Router) {
 // @Produce json
 // @Param request body CreateSubscription true "Subscription data"
 // @Success 201 {object} ResSubscription
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 413 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /subscriptions [post]
 func (c *SubscriptionController) Create(w http.ResponseWriter, r *http.Request) {
 	var data CreateSubscription
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request payload")
+	if err := request.DecodeJSON(r, &data); err != nil {
+		response.Error(w, r, request.StatusCode(err), "Invalid request payload", request.Message(err))
 		return
 	}
 
 	if err := validator.Validate.Struct(data); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		response.Error(w, r, http.StatusBadRequest, "Validation failed", err.Error())
 		return
 	}
 
 	resp, err := c.service.Create(r.Context(), data)
+	if errors.Is(err, ErrInvalidTrial) {
+		response.Error(w, r, http.StatusBadRequest, "Invalid trial", err.Error())
+		return
+	}
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, "Failed to create subscription", err.Error())
+		response.Error(w, r, http.StatusInternalServerError, "Failed to create subscription", err.Error())
 		return
 	}
 
-	responseWriter(w, http.StatusCreated, resp)
+	response.JSON(w, http.StatusCreated, resp)
 }
 
 // GetByID godoc
@@ -67,25 +93,32 @@ func (c *SubscriptionController) Create(w http.ResponseWriter, r *http.Request)
 // @Tags Subscriptions
 // @Produce json
 // @Param id path string true "Subscription ID"
+// @Param as_of query string false "Return the subscription as it was at this time (RFC 3339)"
 // @Success 200 {object} ResSubscription
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /subscriptions/{id} [get]
 func (c *SubscriptionController) GetByID(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid subscription ID")
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
 		return
 	}
 
-	subscription, err := c.service.GetByID(r.Context(), id)
+	asOf, err := parseAsOf(r.URL.Query().Get("as_of"))
 	if err != nil {
-		errorResponse(w, http.StatusNotFound, "Subscription not found")
+		response.Error(w, r, http.StatusBadRequest, "Invalid as_of", err.Error())
 		return
 	}
 
-	responseWriter(w, http.StatusOK, subscription)
+	subscription, err := c.service.GetByID(r.Context(), id, asOf)
+	if err != nil {
+		response.Error(w, r, http.StatusNotFound, "Subscription not found")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, subscription)
 }
 
 // Update godoc
@@ -97,56 +130,64 @@ func (c *SubscriptionController) GetByID(w http.ResponseWriter, r *http.Request)
 // @Param id path string true "Subscription ID"
 // @Param request body UpdateSubscription true "Subscription update data"
 // @Success 200 {object} ResSubscription
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 413 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /subscriptions/{id} [put]
 func (c *SubscriptionController) Update(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid subscription ID")
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
 		return
 	}
 
 	var data UpdateSubscription
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid request payload")
+	if err := request.DecodeJSON(r, &data); err != nil {
+		response.Error(w, r, request.StatusCode(err), "Invalid request payload", request.Message(err))
 		return
 	}
 
 	if err := validator.Validate.Struct(data); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		response.Error(w, r, http.StatusBadRequest, "Validation failed", err.Error())
 		return
 	}
 
 	updated, err := c.service.Update(r.Context(), id, data)
-	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, "Failed to update subscription", err.Error())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(w, r, http.StatusNotFound, "Subscription not found")
+		return
+	case errors.Is(err, ErrInvalidPriceChange):
+		response.Error(w, r, http.StatusBadRequest, "Invalid price change", err.Error())
+		return
+	case err != nil:
+		response.Error(w, r, http.StatusInternalServerError, "Failed to update subscription", err.Error())
 		return
 	}
 
-	responseWriter(w, http.StatusOK, updated)
+	response.JSON(w, http.StatusOK, updated)
 }
 
 // Delete godoc
 // @Summary Delete subscription
-// @Description Deletes a subscription by ID
+// @Description Moves a subscription to the trash; it can be restored until purged
 // @Tags Subscriptions
 // @Param id path string true "Subscription ID"
 // @Success 204
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /subscriptions/{id} [delete]
 func (c *SubscriptionController) Delete(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(mux.Vars(r)["id"])
 	if err != nil {
-		errorResponse(w, http.StatusBadRequest, "Invalid subscription ID")
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
 		return
 	}
 
 	if err := c.service.Delete(r.Context(), id); err != nil {
-		errorResponse(w, http.StatusInternalServerError, "Failed to delete subscription", err.Error())
+		response.Error(w, r, http.StatusInternalServerError, "Failed to delete subscription", err.Error())
 		return
 	}
 
@@ -160,49 +201,594 @@ func (c *SubscriptionController) Delete(w http.ResponseWriter, r *http.Request)
 // @Produce json
 // @Param request query SubscriptionList true "Summary list subctiptions"
 // @Success 200 {array} ResSubscription
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /subscriptions [get]
 func (c *SubscriptionController) List(w http.ResponseWriter, r *http.Request) {
 	filter := SubscriptionList{
 		UserID: r.URL.Query().Get("user_id"),
 		Limit:  r.URL.Query().Get("limit"),
 		Offset: r.URL.Query().Get("offset"),
+		Status: r.URL.Query().Get("status"),
+		AsOf:   r.URL.Query().Get("as_of"),
+
+		TrialEndingSoon: r.URL.Query().Get("trial_ending_soon"),
+	}
+
+	if err := validator.Validate.Struct(filter); err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Validation failed", err.Error())
+		return
 	}
 
 	subscriptions, err := c.service.List(r.Context(), filter)
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, "Failed to list subscriptions", err.Error())
+		response.Error(w, r, http.StatusInternalServerError, "Failed to list subscriptions", err.Error())
 		return
 	}
 
-	responseWriter(w, http.StatusOK, subscriptions)
+	response.JSON(w, http.StatusOK, subscriptions)
 }
 
-func responseWriter(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(data)
+// Trash godoc
+// @Summary List deleted subscriptions
+// @Description Returns subscriptions in the trash, most recently deleted first
+// @Tags Subscriptions
+// @Produce json
+// @Param request query SubscriptionList true "Trash list parameters"
+// @Success 200 {array} ResSubscription
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/trash [get]
+func (c *SubscriptionController) Trash(w http.ResponseWriter, r *http.Request) {
+	filter := SubscriptionList{
+		UserID: r.URL.Query().Get("user_id"),
+		Limit:  r.URL.Query().Get("limit"),
+		Offset: r.URL.Query().Get("offset"),
+	}
+
+	subscriptions, err := c.service.Trash(r.Context(), filter)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, "Failed to list deleted subscriptions", err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, subscriptions)
 }
 
-func errorResponse(w http.ResponseWriter, statusCode int, message string, details ...string) {
-	response := ErrorResponse{
-		Message:    message,
-		Errors:     details,
-		StatusCode: statusCode,
+// Restore godoc
+// @Summary Restore subscription
+// @Description Moves a subscription out of the trash
+// @Tags Subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} ResSubscription
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/restore [post]
+func (c *SubscriptionController) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	restored, err := c.service.Restore(r.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(w, r, http.StatusNotFound, "Subscription not found in trash")
+		return
 	}
-	responseWriter(w, statusCode, response)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, "Failed to restore subscription", err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, restored)
+}
+
+// Pause godoc
+// @Summary Pause a subscription
+// @Description Stops billing an active subscription from the current month
+// @Tags Subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} ResSubscription
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/pause [post]
+func (c *SubscriptionController) Pause(w http.ResponseWriter, r *http.Request) {
+	c.changeStatus(w, r, c.service.Pause)
+}
+
+// Resume godoc
+// @Summary Resume a subscription
+// @Description Bills a paused subscription again from the current month
+// @Tags Subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} ResSubscription
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/resume [post]
+func (c *SubscriptionController) Resume(w http.ResponseWriter, r *http.Request) {
+	c.changeStatus(w, r, c.service.Resume)
+}
+
+// Activate godoc
+// @Summary End a trial
+// @Description Makes a trial subscription active; it is billed from the current month
+// @Tags Subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} ResSubscription
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/activate [post]
+func (c *SubscriptionController) Activate(w http.ResponseWriter, r *http.Request) {
+	c.changeStatus(w, r, c.service.Activate)
+}
+
+// Cancel godoc
+// @Summary Cancel a subscription
+// @Description Cancels a subscription from the current month, or at the end of it with at_period_end
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Param request body CancelSubscription false "Cancellation"
+// @Success 200 {object} ResSubscription
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 413 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/cancel [post]
+func (c *SubscriptionController) Cancel(w http.ResponseWriter, r *http.Request) {
+	var data CancelSubscription
+	if r.ContentLength != 0 {
+		if err := request.DecodeJSON(r, &data); err != nil {
+			response.Error(w, r, request.StatusCode(err), "Invalid request payload", request.Message(err))
+			return
+		}
+	}
+
+	if err := validator.Validate.Struct(data); err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	c.changeStatus(w, r, func(ctx context.Context, id uuid.UUID) (*ResSubscription, error) {
+		return c.service.Cancel(ctx, id, data)
+	})
+}
+
+// changeStatus runs a status transition on the subscription in the path.
+func (c *SubscriptionController) changeStatus(
+	w http.ResponseWriter,
+	r *http.Request,
+	transition func(ctx context.Context, id uuid.UUID) (*ResSubscription, error),
+) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	sub, err := transition(r.Context(), id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(w, r, http.StatusNotFound, "Subscription not found")
+		return
+	case errors.Is(err, ErrInvalidTransition):
+		response.Error(w, r, http.StatusConflict, "Invalid status transition", err.Error())
+		return
+	case err != nil:
+		response.Error(w, r, http.StatusInternalServerError, "Failed to change subscription status", err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, sub)
+}
+
+// ScheduleChange godoc
+// @Summary Schedule a price or plan change
+// @Description Schedules a new price and/or service name from a future month. A change already pending for that month is replaced.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Param request body ScheduleChange true "Scheduled change"
+// @Success 201 {object} ResScheduledChange
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 413 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/changes [post]
+func (c *SubscriptionController) ScheduleChange(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	var data ScheduleChange
+	if err := request.DecodeJSON(r, &data); err != nil {
+		response.Error(w, r, request.StatusCode(err), "Invalid request payload", request.Message(err))
+		return
+	}
+
+	if err := validator.Validate.Struct(data); err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	change, err := c.service.ScheduleChange(r.Context(), id, data)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(w, r, http.StatusNotFound, "Subscription not found")
+		return
+	case errors.Is(err, ErrInvalidChange):
+		response.Error(w, r, http.StatusBadRequest, "Invalid scheduled change", err.Error())
+		return
+	case err != nil:
+		response.Error(w, r, http.StatusInternalServerError, "Failed to schedule change", err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, change)
+}
+
+// ListChanges godoc
+// @Summary List scheduled changes
+// @Description Returns every change scheduled for a subscription, including applied and cancelled ones
+// @Tags Subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {array} ResScheduledChange
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/changes [get]
+func (c *SubscriptionController) ListChanges(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	changes, err := c.service.ListChanges(r.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(w, r, http.StatusNotFound, "Subscription not found")
+		return
+	}
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, "Failed to list scheduled changes", err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, changes)
+}
+
+// CancelChange godoc
+// @Summary Cancel a scheduled change
+// @Description Cancels a change that has not been applied yet
+// @Tags Subscriptions
+// @Param id path string true "Subscription ID"
+// @Param changeId path string true "Change ID"
+// @Success 204
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/changes/{changeId} [delete]
+func (c *SubscriptionController) CancelChange(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+	changeID, err := uuid.Parse(mux.Vars(r)["changeId"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid change ID")
+		return
+	}
+
+	err = c.service.CancelChange(r.Context(), id, changeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(w, r, http.StatusNotFound, "Pending change not found")
+		return
+	}
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, "Failed to cancel change", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddDiscount godoc
+// @Summary Add a discount
+// @Description Attaches a percentage or fixed discount to a subscription, over a month range or a number of billed months
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Param request body AddDiscount true "Discount"
+// @Success 201 {object} ResDiscount
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 413 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/discounts [post]
+func (c *SubscriptionController) AddDiscount(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	var data AddDiscount
+	if err := request.DecodeJSON(r, &data); err != nil {
+		response.Error(w, r, request.StatusCode(err), "Invalid request payload", request.Message(err))
+		return
+	}
+
+	if err := validator.Validate.Struct(data); err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	discount, err := c.service.AddDiscount(r.Context(), id, data)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(w, r, http.StatusNotFound, "Subscription not found")
+		return
+	case errors.Is(err, ErrInvalidDiscount):
+		response.Error(w, r, http.StatusBadRequest, "Invalid discount", err.Error())
+		return
+	case err != nil:
+		response.Error(w, r, http.StatusInternalServerError, "Failed to add discount", err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, discount)
+}
+
+// ListDiscounts godoc
+// @Summary List discounts
+// @Description Returns the current discounts of a subscription in the order they were added
+// @Tags Subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {array} ResDiscount
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/discounts [get]
+func (c *SubscriptionController) ListDiscounts(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	discounts, err := c.service.ListDiscounts(r.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(w, r, http.StatusNotFound, "Subscription not found")
+		return
+	}
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, "Failed to list discounts", err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, discounts)
+}
+
+// RemoveDiscount godoc
+// @Summary Remove a discount
+// @Description Removes a discount from a subscription; summaries as of an earlier time still apply it
+// @Tags Subscriptions
+// @Param id path string true "Subscription ID"
+// @Param discountId path string true "Discount ID"
+// @Success 204
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/discounts/{discountId} [delete]
+func (c *SubscriptionController) RemoveDiscount(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+	discountID, err := uuid.Parse(mux.Vars(r)["discountId"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid discount ID")
+		return
+	}
+
+	err = c.service.RemoveDiscount(r.Context(), id, discountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(w, r, http.StatusNotFound, "Discount not found")
+		return
+	}
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, "Failed to remove discount", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddMember godoc
+// @Summary Share a subscription
+// @Description Adds a user sharing the subscription, with an equal, percent or fixed share of its monthly cost
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Param request body AddMember true "Member"
+// @Success 201 {object} ResMember
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 413 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/members [post]
+func (c *SubscriptionController) AddMember(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	var data AddMember
+	if err := request.DecodeJSON(r, &data); err != nil {
+		response.Error(w, r, request.StatusCode(err), "Invalid request payload", request.Message(err))
+		return
+	}
+
+	if err := validator.Validate.Struct(data); err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	member, err := c.service.AddMember(r.Context(), id, data)
+	if !c.memberError(w, r, err, "Failed to add member") {
+		response.JSON(w, http.StatusCreated, member)
+	}
+}
+
+// UpdateMember godoc
+// @Summary Change a member's share
+// @Description Changes how the share of a user sharing the subscription is computed
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Param userId path string true "Member user ID"
+// @Param request body UpdateMember true "Share"
+// @Success 200 {object} ResMember
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 413 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/members/{userId} [put]
+func (c *SubscriptionController) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var data UpdateMember
+	if err := request.DecodeJSON(r, &data); err != nil {
+		response.Error(w, r, request.StatusCode(err), "Invalid request payload", request.Message(err))
+		return
+	}
+
+	if err := validator.Validate.Struct(data); err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	member, err := c.service.UpdateMember(r.Context(), id, userID, data)
+	if !c.memberError(w, r, err, "Failed to update member") {
+		response.JSON(w, http.StatusOK, member)
+	}
+}
+
+// RemoveMember godoc
+// @Summary Stop sharing a subscription
+// @Description Removes a user sharing the subscription; summaries as of an earlier time still count their share
+// @Tags Subscriptions
+// @Param id path string true "Subscription ID"
+// @Param userId path string true "Member user ID"
+// @Success 204
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/members/{userId} [delete]
+func (c *SubscriptionController) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	err = c.service.RemoveMember(r.Context(), id, userID)
+	if !c.memberError(w, r, err, "Failed to remove member") {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListMembers godoc
+// @Summary List members
+// @Description Returns the users sharing a subscription besides its owner
+// @Tags Subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {array} ResMember
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /subscriptions/{id}/members [get]
+func (c *SubscriptionController) ListMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	members, err := c.service.ListMembers(r.Context(), id)
+	if !c.memberError(w, r, err, "Failed to list members") {
+		response.JSON(w, http.StatusOK, members)
+	}
+}
+
+// memberError writes the response for an error of the member endpoints and
+// reports whether there was one.
+func (c *SubscriptionController) memberError(w http.ResponseWriter, r *http.Request, err error, message string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(w, r, http.StatusNotFound, "Subscription or member not found")
+	case errors.Is(err, ErrInvalidMember):
+		response.Error(w, r, http.StatusBadRequest, "Invalid member", err.Error())
+	case errors.Is(err, ErrMemberExists):
+		response.Error(w, r, http.StatusConflict, "User already shares the subscription")
+	default:
+		response.Error(w, r, http.StatusInternalServerError, message, err.Error())
+	}
+	return true
 }
 
 // GetSubscriptionSummary godoc
 // @Summary Get subscription summary
-// @Description Calculate total cost of subscriptions for selected period with optional filters
+// @Description Calculate total cost of subscriptions for selected period with optional filters. Every active month is billed at the price in effect in that month, including prices scheduled for future months, less its discounts. Reports gross, discount and net totals. With user_id, shared subscriptions count for the user's share, while paid_price keeps the full cost of the subscriptions the user owns.
 // @Tags Subscriptions
 // @Produce json
 // @Param request query SubscriptionSummary true "Summary request parameters"
 // @Success 200 {object} ResSubscriptionSummary
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /subscriptions/summary [get]
 func (c *SubscriptionController) GetSubscriptionSummary(w http.ResponseWriter, r *http.Request) {
 	req := SubscriptionSummary{
@@ -210,10 +796,11 @@ func (c *SubscriptionController) GetSubscriptionSummary(w http.ResponseWriter, r
 		ServiceName: r.URL.Query().Get("service_name"),
 		StartDate:   r.URL.Query().Get("start_date"),
 		EndDate:     r.URL.Query().Get("end_date"),
+		AsOf:        r.URL.Query().Get("as_of"),
 	}
 
 	if err := validator.Validate.Struct(req); err != nil {
-		errorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		response.Error(w, r, http.StatusBadRequest, "Validation failed", err.Error())
 		return
 	}
 
@@ -221,23 +808,42 @@ func (c *SubscriptionController) GetSubscriptionSummary(w http.ResponseWriter, r
 	if req.UserID != "" {
 		parsedID, err := uuid.Parse(req.UserID)
 		if err != nil {
-			errorResponse(w, http.StatusBadRequest, "Invalid user ID format", err.Error())
+			response.Error(w, r, http.StatusBadRequest, "Invalid user ID format", err.Error())
 			return
 		}
 		userID = &parsedID
 	}
 
+	asOf, err := parseAsOf(req.AsOf)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid as_of", err.Error())
+		return
+	}
+
 	summary, err := c.service.GetSubscriptionSummary(
 		r.Context(),
 		userID,
 		req.ServiceName,
 		req.StartDate,
 		req.EndDate,
+		asOf,
 	)
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, "Failed to calculate summary", err.Error())
+		response.Error(w, r, http.StatusInternalServerError, "Failed to calculate summary", err.Error())
 		return
 	}
 
-	responseWriter(w, http.StatusOK, summary)
+	response.JSON(w, http.StatusOK, summary)
+}
+
+// parseAsOf reads the optional as_of query parameter.
+func parseAsOf(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &asOf, nil
 }
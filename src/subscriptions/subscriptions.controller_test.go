@@ -0,0 +1,178 @@
+package subscriptions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"effective_mobile/src/_core/tenant"
+	"effective_mobile/src/audit"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// newServer serves the subscription routes under /api on the in-memory
+// repository, like main does, with a subscription of alice already created;
+// its ID replaces {id} in request paths.
+func newServer(t *testing.T) (http.Handler, uuid.UUID) {
+	t.Helper()
+	service := NewSubscriptionService(NewMemorySubscriptionRepo(), audit.NewAuditService(audit.NewMemoryAuditRepo()))
+
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(tenant.Middleware(&tenantA, false))
+	NewSubscriptionController(service).RegisterRoutes(api)
+
+	rec := serve(r, http.MethodPost, "/api/subscriptions",
+		`{"service_name":"Netflix","price":100,"user_id":"`+alice.String()+`","start_date":"01-2026","end_date":"12-2099"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create = %d %s", rec.Code, rec.Body)
+	}
+	var sub ResSubscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &sub); err != nil {
+		t.Fatal(err)
+	}
+	return r, sub.ID
+}
+
+func serve(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSubscriptionController(t *testing.T) {
+	type step struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		check func(t *testing.T, body []byte)
+	}{
+		{
+			name: "create",
+			steps: []step{{http.MethodPost, "/api/subscriptions",
+				`{"service_name":"Spotify","price":199.5,"user_id":"` + bob.String() + `","start_date":"02-2026","end_date":"06-2026"}`,
+				http.StatusCreated}},
+			check: func(t *testing.T, body []byte) {
+				var sub ResSubscription
+				if err := json.Unmarshal(body, &sub); err != nil {
+					t.Fatal(err)
+				}
+				if sub.ServiceName != "Spotify" || !near(sub.Price, 199.5) || sub.UserID != bob || sub.StartDate != "02-2026" {
+					t.Errorf("created %+v", sub)
+				}
+			},
+		},
+		{
+			name: "create fails validation",
+			steps: []step{{http.MethodPost, "/api/subscriptions",
+				`{"service_name":"S","price":-1,"user_id":"` + bob.String() + `","start_date":"2026-02","end_date":"06-2026"}`,
+				http.StatusBadRequest}},
+		},
+		{
+			name:  "create with malformed JSON",
+			steps: []step{{http.MethodPost, "/api/subscriptions", `{"service_name":`, http.StatusBadRequest}},
+		},
+		{
+			name: "create a trial without trial_end",
+			steps: []step{{http.MethodPost, "/api/subscriptions",
+				`{"service_name":"Spotify","price":100,"user_id":"` + bob.String() + `","start_date":"02-2026","end_date":"06-2026","status":"trial"}`,
+				http.StatusBadRequest}},
+		},
+		{
+			name:  "get",
+			steps: []step{{http.MethodGet, "/api/subscriptions/{id}", "", http.StatusOK}},
+			check: func(t *testing.T, body []byte) {
+				var sub ResSubscription
+				if err := json.Unmarshal(body, &sub); err != nil {
+					t.Fatal(err)
+				}
+				if sub.ServiceName != "Netflix" || sub.UserID != alice {
+					t.Errorf("got %+v", sub)
+				}
+			},
+		},
+		{
+			name:  "get with an invalid ID",
+			steps: []step{{http.MethodGet, "/api/subscriptions/not-a-uuid", "", http.StatusBadRequest}},
+		},
+		{
+			name:  "get an unknown subscription",
+			steps: []step{{http.MethodGet, "/api/subscriptions/" + nobody.String(), "", http.StatusNotFound}},
+		},
+		{
+			name:  "update",
+			steps: []step{{http.MethodPut, "/api/subscriptions/{id}", `{"price":150,"price_effective_from":"03-2026"}`, http.StatusOK}},
+		},
+		{
+			name:  "update an unknown subscription",
+			steps: []step{{http.MethodPut, "/api/subscriptions/" + nobody.String(), `{"price":150}`, http.StatusNotFound}},
+		},
+		{
+			name:  "update a price from before the start",
+			steps: []step{{http.MethodPut, "/api/subscriptions/{id}", `{"price":150,"price_effective_from":"01-2025"}`, http.StatusBadRequest}},
+		},
+		{
+			name: "pause twice",
+			steps: []step{
+				{http.MethodPost, "/api/subscriptions/{id}/pause", "", http.StatusOK},
+				{http.MethodPost, "/api/subscriptions/{id}/pause", "", http.StatusConflict},
+			},
+		},
+		{
+			name: "delete and restore",
+			steps: []step{
+				{http.MethodDelete, "/api/subscriptions/{id}", "", http.StatusNoContent},
+				{http.MethodGet, "/api/subscriptions/{id}", "", http.StatusNotFound},
+				{http.MethodPost, "/api/subscriptions/{id}/restore", "", http.StatusOK},
+			},
+		},
+		{
+			name: "summary",
+			steps: []step{{http.MethodGet,
+				"/api/subscriptions/summary?user_id=" + alice.String() + "&start_date=01-2026&end_date=03-2026", "",
+				http.StatusOK}},
+			check: func(t *testing.T, body []byte) {
+				var summary ResSubscriptionSummary
+				if err := json.Unmarshal(body, &summary); err != nil {
+					t.Fatal(err)
+				}
+				if !near(summary.TotalPrice, 300) {
+					t.Errorf("total_price = %v, want 300", summary.TotalPrice)
+				}
+			},
+		},
+		{
+			name: "summary of an invalid period",
+			steps: []step{{http.MethodGet, "/api/subscriptions/summary?start_date=13-2026&end_date=03-2026", "",
+				http.StatusBadRequest}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, id := newServer(t)
+
+			var rec *httptest.ResponseRecorder
+			for _, s := range tt.steps {
+				rec = serve(h, s.method, strings.ReplaceAll(s.path, "{id}", id.String()), s.body)
+				if rec.Code != s.want {
+					t.Fatalf("%s %s = %d %s, want %d", s.method, s.path, rec.Code, rec.Body, s.want)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, rec.Body.Bytes())
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package subscriptions
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -21,6 +23,23 @@ type CreateSubscription struct {
 
 	// Date when the subscription end (MM-YYYY format)
 	EndDate string `json:"end_date" validate:"required,monthyear"`
+
+	// Initial status, trial or active (default active, or trial with a
+	// trial_end)
+	Status string `json:"status,omitempty" validate:"omitempty,oneof=trial active"`
+
+	// First month of the trial (MM-YYYY format, default start_date)
+	TrialStart string `json:"trial_start,omitempty" validate:"omitempty,monthyear,excluded_without=TrialEnd"`
+
+	// Last month of the trial (MM-YYYY format, required with status trial)
+	TrialEnd string `json:"trial_end,omitempty" validate:"required_if=Status trial,omitempty,monthyear"`
+
+	// Monthly cost during the trial (default free)
+	TrialPrice *float64 `json:"trial_price,omitempty" validate:"omitempty,gte=0,excluded_without=TrialEnd"`
+
+	// Cancel the subscription when the trial ends instead of converting it
+	// to paid
+	CancelAfterTrial bool `json:"cancel_after_trial,omitempty" validate:"excluded_without=TrialEnd"`
 }
 
 // UpdateSubscription
@@ -32,6 +51,10 @@ type UpdateSubscription struct {
 	// New monthly cost
 	Price *float64 `json:"price,omitempty" validate:"omitempty,gt=0"`
 
+	// Month the new price applies from (MM-YYYY format); earlier months keep
+	// their price. Without it the price is corrected for the whole period.
+	PriceEffectiveFrom *string `json:"price_effective_from,omitempty" validate:"omitempty,monthyear,excluded_without=Price"`
+
 	// New start date (MM-YYYY format)
 	StartDate *string `json:"start_date,omitempty" validate:"omitempty,monthyear"`
 
@@ -59,12 +82,179 @@ type ResSubscription struct {
 
 	// Optional subscription end date (MM-YYYY format)
 	EndDate *string `json:"end_date,omitempty"`
+
+	// Lifecycle status: trial, active, paused, cancelled or expired
+	Status string `json:"status"`
+
+	// Month the subscription is cancelled from (MM-YYYY format); set ahead
+	// of time for cancellations at the end of the current month
+	CancelAt *string `json:"cancel_at,omitempty"`
+
+	// When the subscription was cancelled
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+
+	// Reason given for the cancellation
+	CancellationReason *string `json:"cancellation_reason,omitempty"`
+
+	// First month of the trial (MM-YYYY format)
+	TrialStart *string `json:"trial_start,omitempty"`
+
+	// Last month of the trial (MM-YYYY format, required with status trial)
+	TrialEnd *string `json:"trial_end,omitempty"`
+
+	// Monthly cost during the trial, unset when free
+	TrialPrice *float64 `json:"trial_price,omitempty"`
+
+	// When the subscription was moved to the trash, only set for trashed ones
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Price and plan changes scheduled for future months
+	PendingChanges []ResScheduledChange `json:"pending_changes,omitempty"`
+}
+
+// CancelSubscription
+// swagger:model CancelSubscription
+type CancelSubscription struct {
+	// Keep the subscription until the end of the current month instead of
+	// cancelling it right away
+	AtPeriodEnd bool `json:"at_period_end"`
+
+	// Why the subscription is cancelled
+	Reason string `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+// ScheduleChange
+// swagger:model ScheduleChange
+type ScheduleChange struct {
+	// Month the change takes effect, after the current one (MM-YYYY format)
+	EffectiveFrom string `json:"effective_from" validate:"required,monthyear"`
+
+	// New monthly cost
+	Price *float64 `json:"price,omitempty" validate:"required_without=ServiceName,omitempty,gt=0"`
+
+	// New service name or plan
+	ServiceName *string `json:"service_name,omitempty" validate:"required_without=Price,omitempty,min=2,max=100"`
+}
+
+// ResScheduledChange
+// swagger:model ScheduledChangeResponse
+type ResScheduledChange struct {
+	// Unique identifier of the change
+	ID uuid.UUID `json:"id"`
+
+	// Month the change takes effect (MM-YYYY format)
+	EffectiveFrom string `json:"effective_from"`
+
+	// New monthly cost, unset when the price does not change
+	Price *float64 `json:"price,omitempty"`
+
+	// New service name, unset when the plan does not change
+	ServiceName *string `json:"service_name,omitempty"`
+
+	// When the change was scheduled
+	CreatedAt time.Time `json:"created_at"`
+
+	// When the change took effect
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+
+	// When the change was cancelled or replaced
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+}
+
+// AddDiscount
+// swagger:model AddDiscount
+type AddDiscount struct {
+	// percent of the monthly price, or a fixed amount off it
+	Kind string `json:"kind" validate:"required,oneof=percent fixed"`
+
+	// Percentage (up to 100) or amount in RUB
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+
+	// First discounted month (MM-YYYY format)
+	StartDate string `json:"start_date" validate:"required,monthyear"`
+
+	// Last discounted month (MM-YYYY format)
+	EndDate *string `json:"end_date,omitempty" validate:"omitempty,monthyear,excluded_with=Cycles"`
+
+	// Number of billed months discounted from start_date; without it or
+	// end_date the discount lasts as long as the subscription
+	Cycles *int `json:"cycles,omitempty" validate:"omitempty,gt=0"`
+
+	// What the discount is for, e.g. a promo code
+	Description *string `json:"description,omitempty" validate:"omitempty,max=200"`
+}
+
+// ResDiscount
+// swagger:model DiscountResponse
+type ResDiscount struct {
+	// Unique identifier of the discount
+	ID uuid.UUID `json:"id"`
+
+	// percent or fixed
+	Kind string `json:"kind"`
+
+	// Percentage or amount in RUB
+	Amount float64 `json:"amount"`
+
+	// First discounted month (MM-YYYY format)
+	StartDate string `json:"start_date"`
+
+	// Last discounted month (MM-YYYY format)
+	EndDate *string `json:"end_date,omitempty"`
+
+	// Number of billed months discounted
+	Cycles *int `json:"cycles,omitempty"`
+
+	// What the discount is for
+	Description *string `json:"description,omitempty"`
+
+	// When the discount was added
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddMember
+// swagger:model AddMember
+type AddMember struct {
+	// User sharing the subscription
+	UserID string `json:"user_id" validate:"required,uuid4"`
+
+	// How the user's share is computed: equal, percent or fixed
+	Split string `json:"split" validate:"required,oneof=equal percent fixed"`
+
+	// Percentage (up to 100) or monthly amount in RUB; not used with equal
+	Share *float64 `json:"share,omitempty" validate:"required_unless=Split equal,excluded_if=Split equal,omitempty,gt=0"`
+}
+
+// UpdateMember
+// swagger:model UpdateMember
+type UpdateMember struct {
+	// How the user's share is computed: equal, percent or fixed
+	Split string `json:"split" validate:"required,oneof=equal percent fixed"`
+
+	// Percentage (up to 100) or monthly amount in RUB; not used with equal
+	Share *float64 `json:"share,omitempty" validate:"required_unless=Split equal,excluded_if=Split equal,omitempty,gt=0"`
+}
+
+// ResMember
+// swagger:model MemberResponse
+type ResMember struct {
+	// User sharing the subscription
+	UserID uuid.UUID `json:"user_id"`
+
+	// equal, percent or fixed
+	Split string `json:"split"`
+
+	// Percentage or monthly amount in RUB
+	Share *float64 `json:"share,omitempty"`
+
+	// When the member was added or last changed
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // SubscriptionSummary
 // swagger:model SubscriptionSummary
 type SubscriptionSummary struct {
-	// User ID to filter by
+	// User ID to filter by; shared subscriptions count for the user's share
 	UserID string `json:"user_id" validate:"omitempty,uuid4"`
 
 	// Service name to filter by
@@ -75,14 +265,30 @@ type SubscriptionSummary struct {
 
 	// End of the period (MM-YYYY format)
 	EndDate string `json:"end_date" validate:"required,monthyear"`
+
+	// Compute over the data as it was at this time (RFC 3339)
+	AsOf string `json:"as_of" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 }
 
 // ResSubscriptionSummary
 // swagger:model ResSubscriptionSummary
 type ResSubscriptionSummary struct {
-	// Total cost for the period
+	// Total cost for the period after discounts, same as net_price
 	TotalPrice float64 `json:"total_price"`
 
+	// Cost before discounts, each month at the price in effect then
+	GrossPrice float64 `json:"gross_price"`
+
+	// Total of the discounts applied
+	Discount float64 `json:"discount"`
+
+	// Cost after discounts
+	NetPrice float64 `json:"net_price"`
+
+	// What is paid: with user_id, the full net cost of the subscriptions
+	// the user owns, including the shares of their members
+	PaidPrice float64 `json:"paid_price"`
+
 	// Period start (MM-YYYY format)
 	StartDate string `json:"start_date"`
 
@@ -93,19 +299,6 @@ type ResSubscriptionSummary struct {
 	Count int `json:"count"`
 }
 
-// ErrorResponse represents API error response
-// swagger:model ErrorResponse
-type ErrorResponse struct {
-	// Error message
-	Message string `json:"message"`
-
-	// Optional list of detailed errors
-	Errors []string `json:"errors,omitempty"`
-
-	// HTTP status code
-	StatusCode int `json:"status_code"`
-}
-
 // SubscriptionList contains filtering parameters
 // swagger:parameters subscriptionList
 type SubscriptionList struct {
@@ -117,4 +310,13 @@ type SubscriptionList struct {
 
 	// Offset for pagination
 	Offset string `json:"offset"`
+
+	// Lifecycle status to filter by
+	Status string `json:"status" validate:"omitempty,oneof=trial active paused cancelled expired"`
+
+	// Only trials ending this month or the next
+	TrialEndingSoon string `json:"trial_ending_soon" validate:"omitempty,boolean"`
+
+	// List the subscriptions as they were at this time (RFC 3339)
+	AsOf string `json:"as_of" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 }
@@ -3,97 +3,955 @@ package subscriptions
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
+	"effective_mobile/src/_core/actor"
+	"effective_mobile/src/_core/db"
+	"effective_mobile/src/_core/tenant"
 	entities "effective_mobile/src/_entities"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// SubscriptionRepository is the storage behind SubscriptionService. Every
+// method except Stats is scoped to the tenant in ctx.
+//
+// Writes also keep a version history, so GetByIDAsOf and the AsOf options
+// of List and SummarySubscriptions can answer from the data as it was at an
+// earlier time. Price and status entries are kept the same way.
+type SubscriptionRepository interface {
+	Create(ctx context.Context, sub *entities.Subscriptions) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.Subscriptions, error)
+	// GetForUpdate reads a subscription for a change to it: from the primary,
+	// with its row locked until the transaction in ctx ends, so concurrent
+	// changes to the same subscription run one after another.
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*entities.Subscriptions, error)
+	GetByIDAsOf(ctx context.Context, id uuid.UUID, at time.Time) (*entities.Subscriptions, error)
+	Update(ctx context.Context, sub *entities.Subscriptions) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, filter ListOptions) ([]entities.Subscriptions, error)
+	Stats(ctx context.Context, at time.Time) ([]TenantStats, error)
+
+	// SetPrice makes sub.Price effective from the month of from, replacing
+	// the entries from that month on. A nil from corrects the price
+	// retroactively, replacing every entry.
+	SetPrice(ctx context.Context, sub *entities.Subscriptions, from *time.Time) error
+	// SetStatus records status as effective from the month of from,
+	// replacing the entries from that month on. The subscription's own
+	// status fields are saved with Update.
+	SetStatus(ctx context.Context, id uuid.UUID, status string, from time.Time) error
+	// LifecycleDue returns the subscriptions across all tenants that are
+	// past their end date or cancellation month and not yet moved to
+	// expired or cancelled.
+	LifecycleDue(ctx context.Context, month time.Time) ([]entities.Subscriptions, error)
+
+	// SummarySubscriptions returns the subscriptions matching filter with
+	// their price and status entries and pending changes in effective order,
+	// and their discounts and members in the order they were added.
+	SummarySubscriptions(ctx context.Context, filter SummaryOptions) ([]PricedSubscription, error)
+
+	AddDiscount(ctx context.Context, discount *entities.SubscriptionDiscount) error
+	// RemoveDiscount ends a discount; it still applies to summaries as of
+	// an earlier time.
+	RemoveDiscount(ctx context.Context, subID, discountID uuid.UUID) (*entities.SubscriptionDiscount, error)
+	// ListDiscounts returns the current discounts of a subscription in the
+	// order they were added.
+	ListDiscounts(ctx context.Context, subID uuid.UUID) ([]entities.SubscriptionDiscount, error)
+
+	// SetMember adds a member to a subscription, replacing the current entry
+	// of the same user.
+	SetMember(ctx context.Context, member *entities.SubscriptionMember) error
+	RemoveMember(ctx context.Context, subID, userID uuid.UUID) (*entities.SubscriptionMember, error)
+	// ListMembers returns the current members of a subscription in the
+	// order they were added or last changed.
+	ListMembers(ctx context.Context, subID uuid.UUID) ([]entities.SubscriptionMember, error)
+
+	// ScheduleChange stores a pending change, replacing any other change of
+	// the subscription pending for the same month.
+	ScheduleChange(ctx context.Context, change *entities.SubscriptionChange) error
+	CancelChange(ctx context.Context, subID, changeID uuid.UUID) (*entities.SubscriptionChange, error)
+	// ListChanges returns every change of a subscription, including applied
+	// and cancelled ones, in effective order.
+	ListChanges(ctx context.Context, subID uuid.UUID) ([]entities.SubscriptionChange, error)
+	// PendingChanges returns the changes of the given subscriptions pending
+	// at asOf, or now when asOf is nil, in effective order.
+	PendingChanges(ctx context.Context, subIDs []uuid.UUID, asOf *time.Time) ([]entities.SubscriptionChange, error)
+	// DueChanges returns the changes pending for month or earlier across
+	// all tenants, oldest first. Changes of deleted subscriptions wait until
+	// they are restored.
+	DueChanges(ctx context.Context, month time.Time) ([]entities.SubscriptionChange, error)
+	MarkChangeApplied(ctx context.Context, id uuid.UUID, at time.Time) error
+
+	// ListDeleted returns soft-deleted subscriptions, most recently deleted
+	// first. Restore brings one back; Purge permanently removes those
+	// deleted before the given time across all tenants, keeping their
+	// history.
+	ListDeleted(ctx context.Context, filter ListOptions) ([]entities.Subscriptions, error)
+	Restore(ctx context.Context, id uuid.UUID) (*entities.Subscriptions, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+
+	// Transaction runs fn so that the writes made with its ctx, including
+	// those of other repositories sharing the storage, commit together.
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var _ SubscriptionRepository = (*SubscriptionRepo)(nil)
+
+// SubscriptionRepo writes to the primary and reads through the cluster, so
+// List, GetByID and summaries may be served by a replica.
 type SubscriptionRepo struct {
-	db *gorm.DB
+	cluster *db.Cluster
+}
+
+func NewSubscriptionRepo(cluster *db.Cluster) *SubscriptionRepo {
+	return &SubscriptionRepo{cluster: cluster}
 }
 
-func NewSubscriptionRepo(db *gorm.DB) *SubscriptionRepo {
-	return &SubscriptionRepo{db: db}
+func (r *SubscriptionRepo) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.cluster.Transaction(ctx, fn)
 }
 
 func (r *SubscriptionRepo) Create(ctx context.Context, sub *entities.Subscriptions) error {
-	return r.db.WithContext(ctx).Create(sub).Error
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+	sub.TenantID = tenantID
+	sub.Price = roundPrice(sub.Price)
+	if sub.TrialPrice != nil {
+		trialPrice := roundPrice(*sub.TrialPrice)
+		sub.TrialPrice = &trialPrice
+	}
+
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.cluster.Writer(ctx).Create(sub).Error; err != nil {
+			return err
+		}
+		if err := r.addPrice(ctx, sub, sub.StartDate, sub.CreatedAt); err != nil {
+			return err
+		}
+		if err := r.addStatus(ctx, sub.ID, sub.Status, sub.StartDate, sub.CreatedAt); err != nil {
+			return err
+		}
+		return r.openVersion(ctx, sub, sub.CreatedAt)
+	})
+	if err != nil {
+		return err
+	}
+	r.cluster.Wrote(client(ctx))
+	return nil
 }
 
 func (r *SubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*entities.Subscriptions, error) {
 	var sub entities.Subscriptions
-	err := r.db.WithContext(ctx).First(&sub, "id = ?", id).Error
+	err := r.cluster.Reader(ctx, client(ctx)).WithContext(ctx).Scopes(tenant.Scope(ctx)).First(&sub, "id = ?", id).Error
+	return &sub, err
+}
+
+func (r *SubscriptionRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*entities.Subscriptions, error) {
+	var sub entities.Subscriptions
+	query := r.cluster.Writer(ctx).Scopes(tenant.Scope(ctx))
+	if r.cluster.Primary().Dialector.Name() == db.Postgres {
+		// SQLite has no row locks; its single connection already runs
+		// transactions one at a time.
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	err := query.First(&sub, "id = ?", id).Error
+	return &sub, err
+}
+
+func (r *SubscriptionRepo) GetByIDAsOf(ctx context.Context, id uuid.UUID, at time.Time) (*entities.Subscriptions, error) {
+	var sub entities.Subscriptions
+	err := r.reader(ctx, &at).Scopes(tenant.Scope(ctx)).First(&sub, "id = ?", id).Error
 	return &sub, err
 }
 
 func (r *SubscriptionRepo) Update(ctx context.Context, sub *entities.Subscriptions) error {
-	return r.db.WithContext(ctx).Save(sub).Error
+	sub.Price = roundPrice(sub.Price)
+
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		result := r.cluster.Writer(ctx).
+			Model(sub).
+			Scopes(tenant.Scope(ctx)).
+			Select("*").
+			Omit("id", "tenant_id", "created_at", "deleted_at").
+			Updates(sub)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := r.closeVersion(ctx, sub.ID, sub.UpdatedAt); err != nil {
+			return err
+		}
+		return r.openVersion(ctx, sub, sub.UpdatedAt)
+	})
+	if err != nil {
+		return err
+	}
+	r.cluster.Wrote(client(ctx))
+	return nil
 }
 
 func (r *SubscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&entities.Subscriptions{}, "id = ?", id).Error
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		now := time.Now().UTC()
+		result := r.cluster.Writer(ctx).
+			Model(&entities.Subscriptions{}).
+			Scopes(tenant.Scope(ctx)).
+			Where("id = ?", id).
+			UpdateColumn("deleted_at", now)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return r.closeVersion(ctx, id, now)
+	})
+	if err != nil {
+		return err
+	}
+	r.cluster.Wrote(client(ctx))
+	return nil
 }
 
 func (r *SubscriptionRepo) List(ctx context.Context, filter ListOptions) ([]entities.Subscriptions, error) {
 	var subs []entities.Subscriptions
 
-	query := r.db.WithContext(ctx).Model(&entities.Subscriptions{})
+	query := r.reader(ctx, filter.AsOf).
+		Scopes(tenant.Scope(ctx), filter.scope).
+		Order("created_at, id")
 
-	if filter.UserID != nil {
-		query = query.Where("user_id = ?", filter.UserID)
+	if err := query.Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
 	}
 
-	if filter.Limit != nil {
-		query = query.Limit(*filter.Limit)
+	return subs, nil
+}
+
+func (r *SubscriptionRepo) ListDeleted(ctx context.Context, filter ListOptions) ([]entities.Subscriptions, error) {
+	var subs []entities.Subscriptions
+
+	query := r.cluster.Reader(ctx, client(ctx)).WithContext(ctx).
+		Unscoped().
+		Model(&entities.Subscriptions{}).
+		Scopes(tenant.Scope(ctx), filter.scope).
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC, created_at, id")
+
+	if err := query.Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list deleted subscriptions: %w", err)
 	}
 
-	if filter.Offset != nil {
-		query = query.Offset(*filter.Offset)
+	return subs, nil
+}
+
+func (r *SubscriptionRepo) Restore(ctx context.Context, id uuid.UUID) (*entities.Subscriptions, error) {
+	var sub *entities.Subscriptions
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		result := r.cluster.Writer(ctx).
+			Unscoped().
+			Model(&entities.Subscriptions{}).
+			Scopes(tenant.Scope(ctx)).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return fmt.Errorf("failed to restore subscription: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var err error
+		if sub, err = r.GetByID(db.WithPrimary(ctx), id); err != nil {
+			return err
+		}
+		return r.openVersion(ctx, sub, sub.UpdatedAt)
+	})
+	if err != nil {
+		return nil, err
 	}
+	r.cluster.Wrote(client(ctx))
 
-	if err := query.Find(&subs).Error; err != nil {
-		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	return sub, nil
+}
+
+// Purge hard-deletes rows soft-deleted before the cutoff. Their history
+// (versions, prices, statuses, changes, discounts and members) is kept, so
+// as_of queries still see them as they were. Like Stats it is a maintenance
+// job and ignores tenant scoping.
+func (r *SubscriptionRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := r.cluster.Writer(ctx).
+		Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Delete(&entities.Subscriptions{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge deleted subscriptions: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (r *SubscriptionRepo) SetPrice(ctx context.Context, sub *entities.Subscriptions, from *time.Time) error {
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		now := time.Now().UTC()
+		effective := sub.StartDate
+		replaced := r.cluster.Writer(ctx).
+			Model(&entities.SubscriptionPrice{}).
+			Scopes(tenant.Scope(ctx)).
+			Where("subscription_id = ? AND valid_to IS NULL", sub.ID)
+		if from != nil {
+			effective = *from
+			replaced = replaced.Where("effective_from >= ?", *from)
+		}
+
+		if err := replaced.Update("valid_to", now).Error; err != nil {
+			return err
+		}
+		return r.addPrice(ctx, sub, effective, now)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set subscription price: %w", err)
+	}
+	r.cluster.Wrote(client(ctx))
+	return nil
+}
+
+func (r *SubscriptionRepo) SummarySubscriptions(ctx context.Context, filter SummaryOptions) ([]PricedSubscription, error) {
+	// One handle for both queries, so prices come from the same replica.
+	conn := r.cluster.Reader(ctx, client(ctx)).WithContext(ctx)
+
+	var subs []entities.Subscriptions
+	err := subscriptionsAt(conn, filter.AsOf).
+		Scopes(tenant.Scope(ctx), filter.scope).
+		Order("created_at, id").
+		Find(&subs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate summary: %w", err)
+	}
+	if len(subs) == 0 {
+		return nil, nil
+	}
+
+	var prices []entities.SubscriptionPrice
+	ids := subscriptionsAt(conn, filter.AsOf).Scopes(tenant.Scope(ctx), filter.scope).Select("id")
+	err = conn.
+		Scopes(tenant.Scope(ctx), validAt(filter.AsOf)).
+		Where("subscription_id IN (?)", ids).
+		Order("effective_from").
+		Find(&prices).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscription prices: %w", err)
+	}
+
+	var statuses []entities.SubscriptionStatus
+	err = conn.
+		Scopes(tenant.Scope(ctx), validAt(filter.AsOf)).
+		Where("subscription_id IN (?)", ids).
+		Order("effective_from").
+		Find(&statuses).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscription statuses: %w", err)
+	}
+
+	var pending []entities.SubscriptionChange
+	err = conn.
+		Scopes(tenant.Scope(ctx), pendingAt(filter.AsOf)).
+		Where("subscription_id IN (?)", ids).
+		Order("effective_from, created_at").
+		Find(&pending).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scheduled changes: %w", err)
+	}
+
+	var discounts []entities.SubscriptionDiscount
+	err = conn.
+		Scopes(tenant.Scope(ctx), validAt(filter.AsOf)).
+		Where("subscription_id IN (?)", ids).
+		Order("valid_from, id").
+		Find(&discounts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscription discounts: %w", err)
+	}
+
+	var members []entities.SubscriptionMember
+	err = conn.
+		Scopes(tenant.Scope(ctx), validAt(filter.AsOf)).
+		Where("subscription_id IN (?)", ids).
+		Order("valid_from, id").
+		Find(&members).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscription members: %w", err)
+	}
+
+	return withHistory(subs, prices, statuses, pending, discounts, members), nil
+}
+
+func (r *SubscriptionRepo) AddDiscount(ctx context.Context, discount *entities.SubscriptionDiscount) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+	discount.TenantID = tenantID
+	discount.Amount = roundPrice(discount.Amount)
+	discount.ValidFrom = time.Now().UTC()
+
+	if err := r.cluster.Writer(ctx).Create(discount).Error; err != nil {
+		return fmt.Errorf("failed to add discount: %w", err)
+	}
+	r.cluster.Wrote(client(ctx))
+	return nil
+}
+
+func (r *SubscriptionRepo) RemoveDiscount(ctx context.Context, subID, discountID uuid.UUID) (*entities.SubscriptionDiscount, error) {
+	var discount entities.SubscriptionDiscount
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		result := r.cluster.Writer(ctx).
+			Model(&entities.SubscriptionDiscount{}).
+			Scopes(tenant.Scope(ctx), validAt(nil)).
+			Where("id = ? AND subscription_id = ?", discountID, subID).
+			Update("valid_to", time.Now().UTC())
+		if result.Error != nil {
+			return fmt.Errorf("failed to remove discount: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return r.cluster.Writer(ctx).First(&discount, "id = ?", discountID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.cluster.Wrote(client(ctx))
+	return &discount, nil
+}
+
+func (r *SubscriptionRepo) SetMember(ctx context.Context, member *entities.SubscriptionMember) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+	member.TenantID = tenantID
+	if member.Share != nil {
+		share := roundPrice(*member.Share)
+		member.Share = &share
+	}
+	member.ValidFrom = time.Now().UTC()
+
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		err := r.cluster.Writer(ctx).
+			Model(&entities.SubscriptionMember{}).
+			Scopes(tenant.Scope(ctx), validAt(nil)).
+			Where("subscription_id = ? AND user_id = ?", member.SubscriptionID, member.UserID).
+			Update("valid_to", member.ValidFrom).Error
+		if err != nil {
+			return err
+		}
+		return r.cluster.Writer(ctx).Create(member).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set member: %w", err)
+	}
+	r.cluster.Wrote(client(ctx))
+	return nil
+}
+
+func (r *SubscriptionRepo) RemoveMember(ctx context.Context, subID, userID uuid.UUID) (*entities.SubscriptionMember, error) {
+	var member entities.SubscriptionMember
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		err := r.cluster.Writer(ctx).
+			Scopes(tenant.Scope(ctx), validAt(nil)).
+			Where("subscription_id = ? AND user_id = ?", subID, userID).
+			First(&member).Error
+		if err != nil {
+			return err
+		}
+		err = r.cluster.Writer(ctx).
+			Model(&member).
+			Update("valid_to", time.Now().UTC()).Error
+		if err != nil {
+			return fmt.Errorf("failed to remove member: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.cluster.Wrote(client(ctx))
+	return &member, nil
+}
+
+func (r *SubscriptionRepo) ListMembers(ctx context.Context, subID uuid.UUID) ([]entities.SubscriptionMember, error) {
+	var members []entities.SubscriptionMember
+	err := r.cluster.Reader(ctx, client(ctx)).WithContext(ctx).
+		Scopes(tenant.Scope(ctx), validAt(nil)).
+		Where("subscription_id = ?", subID).
+		Order("valid_from, id").
+		Find(&members).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	return members, nil
+}
+
+func (r *SubscriptionRepo) ListDiscounts(ctx context.Context, subID uuid.UUID) ([]entities.SubscriptionDiscount, error) {
+	var discounts []entities.SubscriptionDiscount
+	err := r.cluster.Reader(ctx, client(ctx)).WithContext(ctx).
+		Scopes(tenant.Scope(ctx), validAt(nil)).
+		Where("subscription_id = ?", subID).
+		Order("valid_from, id").
+		Find(&discounts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list discounts: %w", err)
+	}
+	return discounts, nil
+}
+
+func (r *SubscriptionRepo) SetStatus(ctx context.Context, id uuid.UUID, status string, from time.Time) error {
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		now := time.Now().UTC()
+		err := r.cluster.Writer(ctx).
+			Model(&entities.SubscriptionStatus{}).
+			Scopes(tenant.Scope(ctx)).
+			Where("subscription_id = ? AND valid_to IS NULL AND effective_from >= ?", id, from).
+			Update("valid_to", now).Error
+		if err != nil {
+			return err
+		}
+		return r.addStatus(ctx, id, status, from, now)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set subscription status: %w", err)
 	}
+	r.cluster.Wrote(client(ctx))
+	return nil
+}
 
+// LifecycleDue is a maintenance query and ignores tenant scoping. It reads
+// the primary, as the subscriptions are updated right after.
+func (r *SubscriptionRepo) LifecycleDue(ctx context.Context, month time.Time) ([]entities.Subscriptions, error) {
+	var subs []entities.Subscriptions
+	err := r.cluster.Writer(ctx).
+		Where("(status IN ? AND end_date < ?) OR (status NOT IN ? AND cancel_at <= ?) OR (status = ? AND trial_end < ?)",
+			[]string{entities.StatusTrial, entities.StatusActive, entities.StatusPaused}, month,
+			[]string{entities.StatusCancelled, entities.StatusExpired}, month,
+			entities.StatusTrial, month).
+		Order("created_at, id").
+		Find(&subs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions due a status change: %w", err)
+	}
 	return subs, nil
 }
 
-func (r *SubscriptionRepo) GetSubscriptionSummary(
-	ctx context.Context,
-	userID *uuid.UUID,
-	serviceName string,
-	startDate, endDate time.Time,
-) (float64, int, error) {
-	var result struct {
-		Total float64
-		Count int
+func (r *SubscriptionRepo) ScheduleChange(ctx context.Context, change *entities.SubscriptionChange) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+	change.TenantID = tenantID
+	if change.Price != nil {
+		price := roundPrice(*change.Price)
+		change.Price = &price
+	}
+
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		err := r.cluster.Writer(ctx).
+			Model(&entities.SubscriptionChange{}).
+			Scopes(tenant.Scope(ctx), pendingAt(nil)).
+			Where("subscription_id = ? AND effective_from = ?", change.SubscriptionID, change.EffectiveFrom).
+			Update("cancelled_at", time.Now().UTC()).Error
+		if err != nil {
+			return err
+		}
+		return r.cluster.Writer(ctx).Create(change).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule change: %w", err)
+	}
+	r.cluster.Wrote(client(ctx))
+	return nil
+}
+
+func (r *SubscriptionRepo) CancelChange(ctx context.Context, subID, changeID uuid.UUID) (*entities.SubscriptionChange, error) {
+	var change entities.SubscriptionChange
+	err := r.cluster.Transaction(ctx, func(ctx context.Context) error {
+		result := r.cluster.Writer(ctx).
+			Model(&entities.SubscriptionChange{}).
+			Scopes(tenant.Scope(ctx), pendingAt(nil)).
+			Where("id = ? AND subscription_id = ?", changeID, subID).
+			Update("cancelled_at", time.Now().UTC())
+		if result.Error != nil {
+			return fmt.Errorf("failed to cancel change: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return r.cluster.Writer(ctx).First(&change, "id = ?", changeID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.cluster.Wrote(client(ctx))
+	return &change, nil
+}
+
+func (r *SubscriptionRepo) ListChanges(ctx context.Context, subID uuid.UUID) ([]entities.SubscriptionChange, error) {
+	var changes []entities.SubscriptionChange
+	err := r.cluster.Reader(ctx, client(ctx)).WithContext(ctx).
+		Scopes(tenant.Scope(ctx)).
+		Where("subscription_id = ?", subID).
+		Order("effective_from, created_at").
+		Find(&changes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled changes: %w", err)
+	}
+	return changes, nil
+}
+
+func (r *SubscriptionRepo) PendingChanges(ctx context.Context, subIDs []uuid.UUID, asOf *time.Time) ([]entities.SubscriptionChange, error) {
+	if len(subIDs) == 0 {
+		return nil, nil
+	}
+
+	var changes []entities.SubscriptionChange
+	err := r.cluster.Reader(ctx, client(ctx)).WithContext(ctx).
+		Scopes(tenant.Scope(ctx), pendingAt(asOf)).
+		Where("subscription_id IN ?", subIDs).
+		Order("effective_from, created_at").
+		Find(&changes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scheduled changes: %w", err)
+	}
+	return changes, nil
+}
+
+// DueChanges is a maintenance query and ignores tenant scoping. It reads the
+// primary, as the changes are applied right after.
+func (r *SubscriptionRepo) DueChanges(ctx context.Context, month time.Time) ([]entities.SubscriptionChange, error) {
+	var changes []entities.SubscriptionChange
+	live := r.cluster.Writer(ctx).Model(&entities.Subscriptions{}).Select("id")
+	err := r.cluster.Writer(ctx).
+		Scopes(pendingAt(nil)).
+		Where("effective_from <= ? AND subscription_id IN (?)", month, live).
+		Order("effective_from, created_at").
+		Find(&changes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due changes: %w", err)
 	}
+	return changes, nil
+}
 
-	query := r.db.WithContext(ctx).
+func (r *SubscriptionRepo) MarkChangeApplied(ctx context.Context, id uuid.UUID, at time.Time) error {
+	err := r.cluster.Writer(ctx).
+		Model(&entities.SubscriptionChange{}).
+		Scopes(tenant.Scope(ctx)).
+		Where("id = ?", id).
+		Update("applied_at", at).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark change applied: %w", err)
+	}
+	r.cluster.Wrote(client(ctx))
+	return nil
+}
+
+// Stats aggregates the subscriptions billable in the month of at, active or
+// in trial, for every tenant that has any. It is meant for metrics and
+// intentionally ignores tenant scoping.
+func (r *SubscriptionRepo) Stats(ctx context.Context, at time.Time) ([]TenantStats, error) {
+	var stats []TenantStats
+
+	month := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	err := r.cluster.Reader(ctx, "").WithContext(ctx).
 		Model(&entities.Subscriptions{}).
-		Select("COALESCE(SUM(price), 0) as total, COUNT(*) as count").
-		Where("start_date <= ? AND (end_date >= ? OR end_date IS NULL)", endDate, startDate)
+		Select("tenant_id, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) as active", entities.StatusActive).
+		Where("status IN ? AND start_date <= ? AND (end_date >= ? OR end_date IS NULL)",
+			[]string{entities.StatusActive, entities.StatusTrial}, month, month).
+		Group("tenant_id").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect subscription stats: %w", err)
+	}
+
+	return stats, nil
+}
 
-	if userID != nil {
-		query = query.Where("user_id = ?", userID)
+// reader starts a read of the live subscriptions or, with asOf set, of the
+// versions current at that time.
+func (r *SubscriptionRepo) reader(ctx context.Context, asOf *time.Time) *gorm.DB {
+	return subscriptionsAt(r.cluster.Reader(ctx, client(ctx)).WithContext(ctx), asOf)
+}
+
+// subscriptionsAt selects versions under the subscriptions table name and
+// columns, so the usual scopes and filters apply to both.
+func subscriptionsAt(conn *gorm.DB, asOf *time.Time) *gorm.DB {
+	if asOf == nil {
+		return conn.Model(&entities.Subscriptions{})
 	}
 
-	if serviceName != "" {
-		query = query.Where("service_name = ?", serviceName)
+	versions := conn.
+		Model(&entities.SubscriptionVersion{}).
+		Select("subscription_id AS id, tenant_id, service_name, price, user_id, start_date, end_date, " +
+			"created_at, valid_from AS updated_at, NULL AS deleted_at, " +
+			"status, cancel_at, cancelled_at, cancellation_reason, trial_start, trial_end, trial_price").
+		Scopes(validAt(asOf))
+
+	return conn.Unscoped().Table("(?) AS subscriptions", versions)
+}
+
+// validAt keeps the history rows current at asOf, or the current ones when
+// asOf is nil.
+func validAt(asOf *time.Time) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if asOf == nil {
+			return query.Where("valid_to IS NULL")
+		}
+		return query.Where("valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", *asOf, *asOf)
 	}
+}
 
-	if err := query.Scan(&result).Error; err != nil {
-		return 0, 0, fmt.Errorf("failed to calculate summary: %w", err)
+// addStatus records status as effective from the month of effective.
+func (r *SubscriptionRepo) addStatus(ctx context.Context, id uuid.UUID, status string, effective, now time.Time) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
 	}
 
-	return result.Total, result.Count, nil
+	entry := entities.SubscriptionStatus{
+		SubscriptionID: id,
+		TenantID:       tenantID,
+		Status:         status,
+		EffectiveFrom:  effective,
+		ValidFrom:      now,
+	}
+	if err := r.cluster.Writer(ctx).Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to record subscription status: %w", err)
+	}
+	return nil
+}
+
+// pendingAt keeps the changes pending at asOf, or now when asOf is nil.
+func pendingAt(asOf *time.Time) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if asOf == nil {
+			return query.Where("applied_at IS NULL AND cancelled_at IS NULL")
+		}
+		return query.Where("created_at <= ? AND (applied_at IS NULL OR applied_at > ?) AND (cancelled_at IS NULL OR cancelled_at > ?)",
+			*asOf, *asOf, *asOf)
+	}
+}
+
+// addPrice records sub.Price as effective from the month of effective.
+func (r *SubscriptionRepo) addPrice(ctx context.Context, sub *entities.Subscriptions, effective, now time.Time) error {
+	price := entities.SubscriptionPrice{
+		SubscriptionID: sub.ID,
+		TenantID:       sub.TenantID,
+		Price:          sub.Price,
+		EffectiveFrom:  effective,
+		ValidFrom:      now,
+	}
+	if err := r.cluster.Writer(ctx).Create(&price).Error; err != nil {
+		return fmt.Errorf("failed to record subscription price: %w", err)
+	}
+	return nil
+}
+
+// openVersion records the state of sub as current from the given time.
+func (r *SubscriptionRepo) openVersion(ctx context.Context, sub *entities.Subscriptions, from time.Time) error {
+	version := entities.NewSubscriptionVersion(sub, from)
+	if err := r.cluster.Writer(ctx).Create(&version).Error; err != nil {
+		return fmt.Errorf("failed to record subscription version: %w", err)
+	}
+	return nil
+}
+
+// closeVersion ends the current version of a subscription at the given time.
+func (r *SubscriptionRepo) closeVersion(ctx context.Context, id uuid.UUID, at time.Time) error {
+	err := r.cluster.Writer(ctx).
+		Model(&entities.SubscriptionVersion{}).
+		Where("subscription_id = ? AND valid_to IS NULL", id).
+		Update("valid_to", at).Error
+	if err != nil {
+		return fmt.Errorf("failed to close subscription version: %w", err)
+	}
+	return nil
+}
+
+// client keys read-your-writes tracking by tenant and actor, verified or
+// claimed, so one client's writes do not pin the reads of everyone else in
+// the tenant to the primary. Requests naming no actor share a key.
+func client(ctx context.Context) string {
+	id, _ := tenant.FromContext(ctx)
+	return id.String() + "/" + actor.FromContext(ctx) + "/" + actor.ClaimFromContext(ctx)
+}
+
+// roundPrice mirrors the NUMERIC(10,2) column, which SQLite does not enforce.
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
+
+// TenantStats lists a tenant with billable subscriptions and how many of
+// them are active; the rest are in trial.
+type TenantStats struct {
+	TenantID uuid.UUID
+	Active   int64
 }
 
 type ListOptions struct {
 	UserID *uuid.UUID
+	Status string
 	Limit  *int
 	Offset *int
+
+	// TrialEndsBy keeps the trials whose last month is no later than it.
+	TrialEndsBy *time.Time
+
+	// AsOf lists the subscriptions as they were at that time.
+	AsOf *time.Time
+}
+
+// SummaryOptions selects the subscriptions active in a period of months.
+type SummaryOptions struct {
+	// UserID selects the subscriptions the user owns or is a member of.
+	UserID      *uuid.UUID
+	ServiceName string
+	StartDate   time.Time
+	EndDate     time.Time
+
+	// AsOf computes the summary over the data as it was at that time.
+	AsOf *time.Time
+}
+
+func (o SummaryOptions) scope(query *gorm.DB) *gorm.DB {
+	query = query.Where("start_date <= ? AND (end_date >= ? OR end_date IS NULL)", o.EndDate, o.StartDate)
+	if o.UserID != nil {
+		shared := query.Session(&gorm.Session{NewDB: true}).
+			Model(&entities.SubscriptionMember{}).
+			Scopes(validAt(o.AsOf)).
+			Select("subscription_id").
+			Where("user_id = ?", o.UserID)
+		query = query.Where("(user_id = ? OR id IN (?))", o.UserID, shared)
+	}
+	if o.ServiceName != "" {
+		query = query.Where("service_name = ?", o.ServiceName)
+	}
+	return query
+}
+
+// PricedSubscription is a subscription with its price and status entries,
+// pending changes, discounts and members.
+type PricedSubscription struct {
+	entities.Subscriptions
+	Prices    []entities.SubscriptionPrice
+	Statuses  []entities.SubscriptionStatus
+	Pending   []entities.SubscriptionChange
+	Discounts []entities.SubscriptionDiscount
+	Members   []entities.SubscriptionMember
+}
+
+// StatusAt returns the status in effect in month, like PriceAt.
+func (s PricedSubscription) StatusAt(month time.Time) string {
+	status := s.Status
+	if len(s.Statuses) > 0 {
+		status = s.Statuses[0].Status
+	}
+	for _, entry := range s.Statuses {
+		if entry.EffectiveFrom.After(month) {
+			break
+		}
+		status = entry.Status
+	}
+	return status
+}
+
+// PriceAt returns the price in effect in month: that of the last entry
+// effective by then, or of the first entry for earlier months, unless a
+// pending change sets a new price by then.
+func (s PricedSubscription) PriceAt(month time.Time) float64 {
+	price := s.Price
+	if len(s.Prices) > 0 {
+		price = s.Prices[0].Price
+	}
+	for _, entry := range s.Prices {
+		if entry.EffectiveFrom.After(month) {
+			break
+		}
+		price = entry.Price
+	}
+	for _, change := range s.Pending {
+		if change.EffectiveFrom.After(month) {
+			break
+		}
+		if change.Price != nil {
+			price = *change.Price
+		}
+	}
+	return price
+}
+
+// withHistory pairs subs with their price and status entries, pending
+// changes, discounts and members, keeping the order of each.
+func withHistory(
+	subs []entities.Subscriptions,
+	prices []entities.SubscriptionPrice,
+	statuses []entities.SubscriptionStatus,
+	pending []entities.SubscriptionChange,
+	discounts []entities.SubscriptionDiscount,
+	members []entities.SubscriptionMember,
+) []PricedSubscription {
+	pricesByID := make(map[uuid.UUID][]entities.SubscriptionPrice, len(subs))
+	for _, price := range prices {
+		pricesByID[price.SubscriptionID] = append(pricesByID[price.SubscriptionID], price)
+	}
+	statusesByID := make(map[uuid.UUID][]entities.SubscriptionStatus, len(subs))
+	for _, status := range statuses {
+		statusesByID[status.SubscriptionID] = append(statusesByID[status.SubscriptionID], status)
+	}
+	pendingByID := make(map[uuid.UUID][]entities.SubscriptionChange)
+	for _, change := range pending {
+		pendingByID[change.SubscriptionID] = append(pendingByID[change.SubscriptionID], change)
+	}
+	discountsByID := make(map[uuid.UUID][]entities.SubscriptionDiscount)
+	for _, discount := range discounts {
+		discountsByID[discount.SubscriptionID] = append(discountsByID[discount.SubscriptionID], discount)
+	}
+	membersByID := make(map[uuid.UUID][]entities.SubscriptionMember)
+	for _, member := range members {
+		membersByID[member.SubscriptionID] = append(membersByID[member.SubscriptionID], member)
+	}
+
+	priced := make([]PricedSubscription, len(subs))
+	for i, sub := range subs {
+		priced[i] = PricedSubscription{
+			Subscriptions: sub,
+			Prices:        pricesByID[sub.ID],
+			Statuses:      statusesByID[sub.ID],
+			Pending:       pendingByID[sub.ID],
+			Discounts:     discountsByID[sub.ID],
+			Members:       membersByID[sub.ID],
+		}
+	}
+	return priced
+}
+
+func (o ListOptions) scope(query *gorm.DB) *gorm.DB {
+	if o.UserID != nil {
+		query = query.Where("user_id = ?", o.UserID)
+	}
+	if o.Status != "" {
+		query = query.Where("status = ?", o.Status)
+	}
+	if o.TrialEndsBy != nil {
+		query = query.Where("status = ? AND trial_end <= ?", entities.StatusTrial, o.TrialEndsBy)
+	}
+	if o.Limit != nil {
+		query = query.Limit(*o.Limit)
+	}
+	if o.Offset != nil {
+		query = query.Offset(*o.Offset)
+	}
+	return query
 }
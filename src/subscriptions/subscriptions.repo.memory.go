@@ -0,0 +1,888 @@
+package subscriptions
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"effective_mobile/src/_core/tenant"
+	entities "effective_mobile/src/_entities"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var _ SubscriptionRepository = (*MemorySubscriptionRepo)(nil)
+
+// MemorySubscriptionRepo keeps subscriptions in process memory. It mirrors
+// the filtering and summary semantics of SubscriptionRepo and is meant for
+// demos and tests; everything is lost on restart.
+type MemorySubscriptionRepo struct {
+	mu        sync.RWMutex
+	txMu      sync.Mutex
+	subs      map[uuid.UUID]entities.Subscriptions
+	versions  map[uuid.UUID][]entities.SubscriptionVersion
+	prices    map[uuid.UUID][]entities.SubscriptionPrice
+	changes   map[uuid.UUID][]entities.SubscriptionChange
+	statuses  map[uuid.UUID][]entities.SubscriptionStatus
+	discounts map[uuid.UUID][]entities.SubscriptionDiscount
+	members   map[uuid.UUID][]entities.SubscriptionMember
+}
+
+func NewMemorySubscriptionRepo() *MemorySubscriptionRepo {
+	return &MemorySubscriptionRepo{
+		subs:      make(map[uuid.UUID]entities.Subscriptions),
+		versions:  make(map[uuid.UUID][]entities.SubscriptionVersion),
+		prices:    make(map[uuid.UUID][]entities.SubscriptionPrice),
+		changes:   make(map[uuid.UUID][]entities.SubscriptionChange),
+		statuses:  make(map[uuid.UUID][]entities.SubscriptionStatus),
+		discounts: make(map[uuid.UUID][]entities.SubscriptionDiscount),
+		members:   make(map[uuid.UUID][]entities.SubscriptionMember),
+	}
+}
+
+// Transaction runs transactions one at a time so they do not interleave.
+// When fn fails, every write it made is rolled back by restoring the state
+// from before it started. Writes outside a transaction, such as Purge, take
+// the same lock so a rollback cannot undo them.
+func (r *MemorySubscriptionRepo) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+
+	saved := r.snapshot()
+	if err := fn(ctx); err != nil {
+		r.restore(saved)
+		return err
+	}
+	return nil
+}
+
+// memoryState is a copy of the repository contents for rolling back a
+// transaction.
+type memoryState struct {
+	subs      map[uuid.UUID]entities.Subscriptions
+	versions  map[uuid.UUID][]entities.SubscriptionVersion
+	prices    map[uuid.UUID][]entities.SubscriptionPrice
+	changes   map[uuid.UUID][]entities.SubscriptionChange
+	statuses  map[uuid.UUID][]entities.SubscriptionStatus
+	discounts map[uuid.UUID][]entities.SubscriptionDiscount
+	members   map[uuid.UUID][]entities.SubscriptionMember
+}
+
+func (r *MemorySubscriptionRepo) snapshot() memoryState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return memoryState{
+		subs:      maps.Clone(r.subs),
+		versions:  cloneEntries(r.versions),
+		prices:    cloneEntries(r.prices),
+		changes:   cloneEntries(r.changes),
+		statuses:  cloneEntries(r.statuses),
+		discounts: cloneEntries(r.discounts),
+		members:   cloneEntries(r.members),
+	}
+}
+
+func (r *MemorySubscriptionRepo) restore(state memoryState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subs = state.subs
+	r.versions = state.versions
+	r.prices = state.prices
+	r.changes = state.changes
+	r.statuses = state.statuses
+	r.discounts = state.discounts
+	r.members = state.members
+}
+
+// cloneEntries copies the slices too, since entries are updated in place.
+func cloneEntries[T any](entries map[uuid.UUID][]T) map[uuid.UUID][]T {
+	cloned := make(map[uuid.UUID][]T, len(entries))
+	for id, list := range entries {
+		cloned[id] = slices.Clone(list)
+	}
+	return cloned
+}
+
+func (r *MemorySubscriptionRepo) Create(ctx context.Context, sub *entities.Subscriptions) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+
+	now := time.Now().UTC()
+	sub.TenantID = tenantID
+	if sub.ID == uuid.Nil {
+		sub.ID = uuid.New()
+	}
+	sub.Price = roundPrice(sub.Price)
+	if sub.TrialPrice != nil {
+		trialPrice := roundPrice(*sub.TrialPrice)
+		sub.TrialPrice = &trialPrice
+	}
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.subs[sub.ID]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	r.subs[sub.ID] = *sub
+	r.addPrice(sub, sub.StartDate, now)
+	r.addStatus(sub.ID, sub.TenantID, sub.Status, sub.StartDate, now)
+	r.openVersion(sub, now)
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*entities.Subscriptions, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, ok := r.live(tenantID, id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &sub, nil
+}
+
+// GetForUpdate needs no row lock: transactions already run one at a time.
+func (r *MemorySubscriptionRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*entities.Subscriptions, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r *MemorySubscriptionRepo) GetByIDAsOf(ctx context.Context, id uuid.UUID, at time.Time) (*entities.Subscriptions, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, version := range r.versions[id] {
+		if version.TenantID == tenantID && version.ValidAt(at) {
+			sub := version.Subscription()
+			return &sub, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemorySubscriptionRepo) Update(ctx context.Context, sub *entities.Subscriptions) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.live(tenantID, sub.ID)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	updated := *sub
+	updated.TenantID = stored.TenantID
+	updated.CreatedAt = stored.CreatedAt
+	updated.DeletedAt = stored.DeletedAt
+	updated.UpdatedAt = time.Now().UTC()
+	updated.Price = roundPrice(updated.Price)
+	r.subs[sub.ID] = updated
+	r.closeVersion(sub.ID, updated.UpdatedAt)
+	r.openVersion(&updated, updated.UpdatedAt)
+
+	sub.UpdatedAt = updated.UpdatedAt
+	sub.Price = updated.Price
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sub, ok := r.live(tenantID, id); ok {
+		sub.DeletedAt = gorm.DeletedAt{Time: time.Now().UTC(), Valid: true}
+		r.subs[id] = sub
+		r.closeVersion(id, sub.DeletedAt.Time)
+	}
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) List(ctx context.Context, filter ListOptions) ([]entities.Subscriptions, error) {
+	subs, err := r.tenantSubs(ctx, false, filter.AsOf, func(sub *entities.Subscriptions) bool {
+		return (filter.UserID == nil || sub.UserID == *filter.UserID) &&
+			(filter.Status == "" || sub.Status == filter.Status) &&
+			(filter.TrialEndsBy == nil || sub.Status == entities.StatusTrial &&
+				sub.TrialEnd != nil && !sub.TrialEnd.After(*filter.TrialEndsBy))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return filter.page(subs), nil
+}
+
+func (r *MemorySubscriptionRepo) ListDeleted(ctx context.Context, filter ListOptions) ([]entities.Subscriptions, error) {
+	subs, err := r.tenantSubs(ctx, true, nil, func(sub *entities.Subscriptions) bool {
+		return (filter.UserID == nil || sub.UserID == *filter.UserID) &&
+			(filter.Status == "" || sub.Status == filter.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(subs, func(a, b entities.Subscriptions) int {
+		return b.DeletedAt.Time.Compare(a.DeletedAt.Time)
+	})
+
+	return filter.page(subs), nil
+}
+
+func (r *MemorySubscriptionRepo) Restore(ctx context.Context, id uuid.UUID) (*entities.Subscriptions, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subs[id]
+	if !ok || sub.TenantID != tenantID || !sub.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	sub.DeletedAt = gorm.DeletedAt{}
+	sub.UpdatedAt = time.Now().UTC()
+	r.subs[id] = sub
+	r.openVersion(&sub, sub.UpdatedAt)
+
+	return &sub, nil
+}
+
+func (r *MemorySubscriptionRepo) Purge(_ context.Context, before time.Time) (int64, error) {
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, sub := range r.subs {
+		if sub.DeletedAt.Valid && sub.DeletedAt.Time.Before(before) {
+			delete(r.subs, id)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+func (r *MemorySubscriptionRepo) SetPrice(ctx context.Context, sub *entities.Subscriptions, from *time.Time) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.live(tenantID, sub.ID); !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	now := time.Now().UTC()
+	effective := sub.StartDate
+	if from != nil {
+		effective = *from
+	}
+	for i := range r.prices[sub.ID] {
+		price := &r.prices[sub.ID][i]
+		if price.ValidTo == nil && (from == nil || !price.EffectiveFrom.Before(*from)) {
+			price.ValidTo = &now
+		}
+	}
+	r.addPrice(sub, effective, now)
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) SummarySubscriptions(ctx context.Context, filter SummaryOptions) ([]PricedSubscription, error) {
+	var shared map[uuid.UUID]bool
+	if filter.UserID != nil {
+		shared = r.sharedWith(*filter.UserID, filter.AsOf)
+	}
+
+	subs, err := r.tenantSubs(ctx, false, filter.AsOf, func(sub *entities.Subscriptions) bool {
+		return overlaps(sub, filter.StartDate, filter.EndDate) &&
+			(filter.UserID == nil || sub.UserID == *filter.UserID || shared[sub.ID]) &&
+			(filter.ServiceName == "" || sub.ServiceName == filter.ServiceName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var (
+		prices    []entities.SubscriptionPrice
+		statuses  []entities.SubscriptionStatus
+		pending   []entities.SubscriptionChange
+		discounts []entities.SubscriptionDiscount
+		members   []entities.SubscriptionMember
+	)
+	for _, sub := range subs {
+		for _, price := range r.prices[sub.ID] {
+			if (filter.AsOf == nil && price.ValidTo == nil) || (filter.AsOf != nil && price.ValidAt(*filter.AsOf)) {
+				prices = append(prices, price)
+			}
+		}
+		for _, status := range r.statuses[sub.ID] {
+			if (filter.AsOf == nil && status.ValidTo == nil) || (filter.AsOf != nil && status.ValidAt(*filter.AsOf)) {
+				statuses = append(statuses, status)
+			}
+		}
+		pending = append(pending, r.pending(sub.ID, filter.AsOf)...)
+		for _, discount := range r.discounts[sub.ID] {
+			if (filter.AsOf == nil && discount.ValidTo == nil) || (filter.AsOf != nil && discount.ValidAt(*filter.AsOf)) {
+				discounts = append(discounts, discount)
+			}
+		}
+		for _, member := range r.members[sub.ID] {
+			if (filter.AsOf == nil && member.ValidTo == nil) || (filter.AsOf != nil && member.ValidAt(*filter.AsOf)) {
+				members = append(members, member)
+			}
+		}
+	}
+	slices.SortStableFunc(prices, func(a, b entities.SubscriptionPrice) int {
+		return a.EffectiveFrom.Compare(b.EffectiveFrom)
+	})
+	slices.SortStableFunc(statuses, func(a, b entities.SubscriptionStatus) int {
+		return a.EffectiveFrom.Compare(b.EffectiveFrom)
+	})
+	sortChanges(pending)
+
+	return withHistory(subs, prices, statuses, pending, discounts, members), nil
+}
+
+// sharedWith returns the IDs of the subscriptions userID is a member of at
+// asOf, or now when asOf is nil.
+func (r *MemorySubscriptionRepo) sharedWith(userID uuid.UUID, asOf *time.Time) map[uuid.UUID]bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	shared := make(map[uuid.UUID]bool)
+	for subID, members := range r.members {
+		for _, member := range members {
+			if member.UserID == userID &&
+				((asOf == nil && member.ValidTo == nil) || (asOf != nil && member.ValidAt(*asOf))) {
+				shared[subID] = true
+			}
+		}
+	}
+	return shared
+}
+
+func (r *MemorySubscriptionRepo) SetMember(ctx context.Context, member *entities.SubscriptionMember) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+	member.TenantID = tenantID
+	if member.Share != nil {
+		share := roundPrice(*member.Share)
+		member.Share = &share
+	}
+	member.ValidFrom = time.Now().UTC()
+	if member.ID == uuid.Nil {
+		member.ID = uuid.New()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.members[member.SubscriptionID] {
+		current := &r.members[member.SubscriptionID][i]
+		if current.UserID == member.UserID && current.TenantID == tenantID && current.ValidTo == nil {
+			replaced := member.ValidFrom
+			current.ValidTo = &replaced
+		}
+	}
+	r.members[member.SubscriptionID] = append(r.members[member.SubscriptionID], *member)
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) RemoveMember(ctx context.Context, subID, userID uuid.UUID) (*entities.SubscriptionMember, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.members[subID] {
+		member := &r.members[subID][i]
+		if member.UserID == userID && member.TenantID == tenantID && member.ValidTo == nil {
+			now := time.Now().UTC()
+			member.ValidTo = &now
+			removed := *member
+			return &removed, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemorySubscriptionRepo) ListMembers(ctx context.Context, subID uuid.UUID) ([]entities.SubscriptionMember, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var members []entities.SubscriptionMember
+	for _, member := range r.members[subID] {
+		if member.TenantID == tenantID && member.ValidTo == nil {
+			members = append(members, member)
+		}
+	}
+
+	return members, nil
+}
+
+func (r *MemorySubscriptionRepo) AddDiscount(ctx context.Context, discount *entities.SubscriptionDiscount) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+	discount.TenantID = tenantID
+	discount.Amount = roundPrice(discount.Amount)
+	discount.ValidFrom = time.Now().UTC()
+	if discount.ID == uuid.Nil {
+		discount.ID = uuid.New()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.discounts[discount.SubscriptionID] = append(r.discounts[discount.SubscriptionID], *discount)
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) RemoveDiscount(ctx context.Context, subID, discountID uuid.UUID) (*entities.SubscriptionDiscount, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.discounts[subID] {
+		discount := &r.discounts[subID][i]
+		if discount.ID == discountID && discount.TenantID == tenantID && discount.ValidTo == nil {
+			now := time.Now().UTC()
+			discount.ValidTo = &now
+			removed := *discount
+			return &removed, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemorySubscriptionRepo) ListDiscounts(ctx context.Context, subID uuid.UUID) ([]entities.SubscriptionDiscount, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var discounts []entities.SubscriptionDiscount
+	for _, discount := range r.discounts[subID] {
+		if discount.TenantID == tenantID && discount.ValidTo == nil {
+			discounts = append(discounts, discount)
+		}
+	}
+
+	return discounts, nil
+}
+
+func (r *MemorySubscriptionRepo) SetStatus(ctx context.Context, id uuid.UUID, status string, from time.Time) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range r.statuses[id] {
+		entry := &r.statuses[id][i]
+		if entry.TenantID == tenantID && entry.ValidTo == nil && !entry.EffectiveFrom.Before(from) {
+			entry.ValidTo = &now
+		}
+	}
+	r.addStatus(id, tenantID, status, from, now)
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) LifecycleDue(_ context.Context, month time.Time) ([]entities.Subscriptions, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var subs []entities.Subscriptions
+	for _, sub := range r.subs {
+		if sub.DeletedAt.Valid {
+			continue
+		}
+		ended := sub.EndDate != nil && sub.EndDate.Before(month) &&
+			(sub.Status == entities.StatusTrial || sub.Status == entities.StatusActive || sub.Status == entities.StatusPaused)
+		cancelled := sub.CancelAt != nil && !sub.CancelAt.After(month) &&
+			sub.Status != entities.StatusCancelled && sub.Status != entities.StatusExpired
+		converted := sub.Status == entities.StatusTrial && sub.TrialEnd != nil && sub.TrialEnd.Before(month)
+		if ended || cancelled || converted {
+			subs = append(subs, sub)
+		}
+	}
+	slices.SortFunc(subs, func(a, b entities.Subscriptions) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	return subs, nil
+}
+
+func (r *MemorySubscriptionRepo) ScheduleChange(ctx context.Context, change *entities.SubscriptionChange) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range r.changes[change.SubscriptionID] {
+		other := &r.changes[change.SubscriptionID][i]
+		if other.TenantID == tenantID && other.Pending() && other.EffectiveFrom.Equal(change.EffectiveFrom) {
+			other.CancelledAt = &now
+		}
+	}
+
+	change.TenantID = tenantID
+	if change.ID == uuid.Nil {
+		change.ID = uuid.New()
+	}
+	if change.Price != nil {
+		price := roundPrice(*change.Price)
+		change.Price = &price
+	}
+	change.CreatedAt = now
+	r.changes[change.SubscriptionID] = append(r.changes[change.SubscriptionID], *change)
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) CancelChange(ctx context.Context, subID, changeID uuid.UUID) (*entities.SubscriptionChange, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.changes[subID] {
+		change := &r.changes[subID][i]
+		if change.ID == changeID && change.TenantID == tenantID && change.Pending() {
+			now := time.Now().UTC()
+			change.CancelledAt = &now
+			cancelled := *change
+			return &cancelled, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemorySubscriptionRepo) ListChanges(ctx context.Context, subID uuid.UUID) ([]entities.SubscriptionChange, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var changes []entities.SubscriptionChange
+	for _, change := range r.changes[subID] {
+		if change.TenantID == tenantID {
+			changes = append(changes, change)
+		}
+	}
+	sortChanges(changes)
+
+	return changes, nil
+}
+
+func (r *MemorySubscriptionRepo) PendingChanges(ctx context.Context, subIDs []uuid.UUID, asOf *time.Time) ([]entities.SubscriptionChange, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var changes []entities.SubscriptionChange
+	for _, id := range subIDs {
+		for _, change := range r.pending(id, asOf) {
+			if change.TenantID == tenantID {
+				changes = append(changes, change)
+			}
+		}
+	}
+	sortChanges(changes)
+
+	return changes, nil
+}
+
+func (r *MemorySubscriptionRepo) DueChanges(_ context.Context, month time.Time) ([]entities.SubscriptionChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var changes []entities.SubscriptionChange
+	for id, subChanges := range r.changes {
+		if sub, ok := r.subs[id]; !ok || sub.DeletedAt.Valid {
+			continue
+		}
+		for _, change := range subChanges {
+			if change.Pending() && !change.EffectiveFrom.After(month) {
+				changes = append(changes, change)
+			}
+		}
+	}
+	sortChanges(changes)
+
+	return changes, nil
+}
+
+func (r *MemorySubscriptionRepo) MarkChangeApplied(ctx context.Context, id uuid.UUID, at time.Time) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrMissing
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, subChanges := range r.changes {
+		for i := range subChanges {
+			if subChanges[i].ID == id && subChanges[i].TenantID == tenantID {
+				subChanges[i].AppliedAt = &at
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) Stats(ctx context.Context, at time.Time) ([]TenantStats, error) {
+	month := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byTenant := make(map[uuid.UUID]*TenantStats)
+	for _, sub := range r.subs {
+		billable := sub.Status == entities.StatusActive || sub.Status == entities.StatusTrial
+		if sub.DeletedAt.Valid || !billable || !overlaps(&sub, month, month) {
+			continue
+		}
+		stat, ok := byTenant[sub.TenantID]
+		if !ok {
+			stat = &TenantStats{TenantID: sub.TenantID}
+			byTenant[sub.TenantID] = stat
+		}
+		if sub.Status == entities.StatusActive {
+			stat.Active++
+		}
+	}
+
+	stats := make([]TenantStats, 0, len(byTenant))
+	for _, stat := range byTenant {
+		stats = append(stats, *stat)
+	}
+
+	return stats, nil
+}
+
+// live returns the tenant's subscription unless it is missing or deleted.
+// Callers hold the lock.
+func (r *MemorySubscriptionRepo) live(tenantID, id uuid.UUID) (entities.Subscriptions, bool) {
+	sub, ok := r.subs[id]
+	if !ok || sub.TenantID != tenantID || sub.DeletedAt.Valid {
+		return entities.Subscriptions{}, false
+	}
+	return sub, true
+}
+
+// openVersion starts a version of sub valid from the given time. Callers
+// hold the lock.
+func (r *MemorySubscriptionRepo) openVersion(sub *entities.Subscriptions, from time.Time) {
+	version := entities.NewSubscriptionVersion(sub, from)
+	version.ID = uuid.New()
+	r.versions[sub.ID] = append(r.versions[sub.ID], version)
+}
+
+// addPrice records sub.Price as effective from the month of effective.
+// Callers hold the lock.
+func (r *MemorySubscriptionRepo) addPrice(sub *entities.Subscriptions, effective, now time.Time) {
+	r.prices[sub.ID] = append(r.prices[sub.ID], entities.SubscriptionPrice{
+		ID:             uuid.New(),
+		SubscriptionID: sub.ID,
+		TenantID:       sub.TenantID,
+		Price:          sub.Price,
+		EffectiveFrom:  effective,
+		ValidFrom:      now,
+	})
+}
+
+// addStatus records status as effective from the month of effective.
+// Callers hold the lock.
+func (r *MemorySubscriptionRepo) addStatus(id, tenantID uuid.UUID, status string, effective, now time.Time) {
+	r.statuses[id] = append(r.statuses[id], entities.SubscriptionStatus{
+		ID:             uuid.New(),
+		SubscriptionID: id,
+		TenantID:       tenantID,
+		Status:         status,
+		EffectiveFrom:  effective,
+		ValidFrom:      now,
+	})
+}
+
+// pending returns the changes of a subscription pending at asOf, or now when
+// asOf is nil. Callers hold the lock.
+func (r *MemorySubscriptionRepo) pending(id uuid.UUID, asOf *time.Time) []entities.SubscriptionChange {
+	var pending []entities.SubscriptionChange
+	for _, change := range r.changes[id] {
+		if (asOf == nil && change.Pending()) || (asOf != nil && change.PendingAt(*asOf)) {
+			pending = append(pending, change)
+		}
+	}
+	return pending
+}
+
+// sortChanges orders changes like the SQL queries: by effective month, then
+// by when they were scheduled.
+func sortChanges(changes []entities.SubscriptionChange) {
+	slices.SortStableFunc(changes, func(a, b entities.SubscriptionChange) int {
+		if c := a.EffectiveFrom.Compare(b.EffectiveFrom); c != 0 {
+			return c
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
+
+// closeVersion ends the current version of a subscription. Callers hold the
+// lock.
+func (r *MemorySubscriptionRepo) closeVersion(id uuid.UUID, at time.Time) {
+	versions := r.versions[id]
+	if n := len(versions); n > 0 && versions[n-1].ValidTo == nil {
+		versions[n-1].ValidTo = &at
+	}
+}
+
+// tenantSubs returns copies of the tenant's live or deleted subscriptions
+// matching keep in creation order, so pagination is stable. With asOf set it
+// returns the versions current at that time instead.
+func (r *MemorySubscriptionRepo) tenantSubs(ctx context.Context, deleted bool, asOf *time.Time, keep func(*entities.Subscriptions) bool) ([]entities.Subscriptions, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissing
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var subs []entities.Subscriptions
+	if asOf != nil {
+		for _, versions := range r.versions {
+			for _, version := range versions {
+				if version.TenantID != tenantID || !version.ValidAt(*asOf) {
+					continue
+				}
+				if sub := version.Subscription(); keep(&sub) {
+					subs = append(subs, sub)
+				}
+			}
+		}
+	} else {
+		for _, sub := range r.subs {
+			if sub.TenantID == tenantID && sub.DeletedAt.Valid == deleted && keep(&sub) {
+				subs = append(subs, sub)
+			}
+		}
+	}
+
+	slices.SortFunc(subs, func(a, b entities.Subscriptions) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	return subs, nil
+}
+
+// page applies Limit and Offset to rows filtered in memory.
+func (o ListOptions) page(subs []entities.Subscriptions) []entities.Subscriptions {
+	if o.Offset != nil {
+		subs = subs[min(*o.Offset, len(subs)):]
+	}
+	if o.Limit != nil {
+		subs = subs[:min(*o.Limit, len(subs))]
+	}
+	return subs
+}
+
+// overlaps matches the SQL condition
+// start_date <= end AND (end_date >= start OR end_date IS NULL).
+func overlaps(sub *entities.Subscriptions, start, end time.Time) bool {
+	return !sub.StartDate.After(end) && (sub.EndDate == nil || !sub.EndDate.Before(start))
+}
@@ -0,0 +1,488 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"effective_mobile/src/_core/config"
+	"effective_mobile/src/_core/db"
+	"effective_mobile/src/_core/tenant"
+	entities "effective_mobile/src/_entities"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	tenantA = uuid.MustParse("00000000-0000-4000-8000-0000000000a1")
+	tenantB = uuid.MustParse("00000000-0000-4000-8000-0000000000b1")
+)
+
+// repos returns the repository implementations the contract tests run
+// against: the in-memory one and the SQL one on a migrated SQLite file.
+func repos() map[string]func(t *testing.T) SubscriptionRepository {
+	return map[string]func(t *testing.T) SubscriptionRepository{
+		"memory": func(t *testing.T) SubscriptionRepository {
+			return NewMemorySubscriptionRepo()
+		},
+		"sqlite": func(t *testing.T) SubscriptionRepository {
+			cfg := config.Default()
+			cfg.Storage = db.SQLite
+			cfg.SQLite.Path = filepath.Join(t.TempDir(), "subscriptions.db")
+
+			ctx := context.Background()
+			gormDB, err := db.Connect(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close(gormDB) })
+			if err := db.RunMigrations(ctx, gormDB, "up"); err != nil {
+				t.Fatal(err)
+			}
+			cluster, err := db.NewCluster(gormDB, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return NewSubscriptionRepo(cluster)
+		},
+	}
+}
+
+func create(t *testing.T, ctx context.Context, repo SubscriptionRepository, userID uuid.UUID, name string, price float64, status string) *entities.Subscriptions {
+	t.Helper()
+	sub := &entities.Subscriptions{
+		ServiceName: name,
+		Price:       price,
+		UserID:      userID,
+		StartDate:   month(t, "01-2026"),
+		EndDate:     ptr(month(t, "12-2026")),
+		Status:      status,
+	}
+	if err := repo.Create(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func ids(subs []entities.Subscriptions) []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(subs))
+	for _, sub := range subs {
+		result = append(result, sub.ID)
+	}
+	return sortIDs(result)
+}
+
+func sortIDs(list []uuid.UUID) []uuid.UUID {
+	slices.SortFunc(list, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+	return list
+}
+
+func sameIDs(got []entities.Subscriptions, want ...*entities.Subscriptions) bool {
+	expected := make([]entities.Subscriptions, 0, len(want))
+	for _, sub := range want {
+		expected = append(expected, *sub)
+	}
+	return slices.Equal(ids(got), ids(expected))
+}
+
+func TestSubscriptionRepository(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, repo SubscriptionRepository)
+	}{
+		{
+			name: "create and get",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				sub := create(t, ctx, repo, alice, "Netflix", 399.999, entities.StatusActive)
+				if sub.ID == uuid.Nil || sub.TenantID != tenantA {
+					t.Fatalf("created %v in tenant %v", sub.ID, sub.TenantID)
+				}
+
+				for name, get := range map[string]func(context.Context, uuid.UUID) (*entities.Subscriptions, error){
+					"GetByID":      repo.GetByID,
+					"GetForUpdate": repo.GetForUpdate,
+				} {
+					got, err := get(ctx, sub.ID)
+					if err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					if got.ServiceName != "Netflix" || !near(got.Price, 400) || got.UserID != alice ||
+						!got.StartDate.Equal(sub.StartDate) || got.EndDate == nil || !got.EndDate.Equal(*sub.EndDate) {
+						t.Errorf("%s = %+v", name, got)
+					}
+				}
+			},
+		},
+		{
+			name: "other tenants do not see the subscription",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				sub := create(t, ctx, repo, alice, "Netflix", 100, entities.StatusActive)
+				other := tenant.WithID(context.Background(), tenantB)
+
+				if _, err := repo.GetByID(other, sub.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Errorf("GetByID error = %v, want not found", err)
+				}
+				if subs, err := repo.List(other, ListOptions{}); err != nil || len(subs) != 0 {
+					t.Errorf("List = %d subscriptions, %v; want none", len(subs), err)
+				}
+				changed := *sub
+				changed.Price = 1
+				if err := repo.Update(other, &changed); !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Errorf("Update error = %v, want not found", err)
+				}
+			},
+		},
+		{
+			name: "update",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				sub := create(t, ctx, repo, alice, "Netflix", 100, entities.StatusActive)
+				sub.ServiceName = "Netflix Premium"
+				sub.Price = 250
+				if err := repo.Update(ctx, sub); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := repo.GetByID(ctx, sub.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.ServiceName != "Netflix Premium" || !near(got.Price, 250) {
+					t.Errorf("GetByID = %+v", got)
+				}
+
+				missing := *sub
+				missing.ID = uuid.New()
+				if err := repo.Update(ctx, &missing); !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Errorf("Update of a missing subscription error = %v, want not found", err)
+				}
+			},
+		},
+		{
+			name: "list filters",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				netflix := create(t, ctx, repo, alice, "Netflix", 100, entities.StatusActive)
+				spotify := create(t, ctx, repo, alice, "Spotify", 200, entities.StatusPaused)
+				youtube := create(t, ctx, repo, bob, "YouTube", 300, entities.StatusActive)
+
+				tests := []struct {
+					name   string
+					filter ListOptions
+					want   []*entities.Subscriptions
+				}{
+					{"all", ListOptions{}, []*entities.Subscriptions{netflix, spotify, youtube}},
+					{"user", ListOptions{UserID: &alice}, []*entities.Subscriptions{netflix, spotify}},
+					{"status", ListOptions{Status: entities.StatusActive}, []*entities.Subscriptions{netflix, youtube}},
+					{"user and status", ListOptions{UserID: &alice, Status: entities.StatusPaused}, []*entities.Subscriptions{spotify}},
+					{"unknown user", ListOptions{UserID: &nobody}, nil},
+				}
+				for _, tt := range tests {
+					got, err := repo.List(ctx, tt.filter)
+					if err != nil {
+						t.Fatalf("%s: %v", tt.name, err)
+					}
+					if !sameIDs(got, tt.want...) {
+						t.Errorf("%s: List = %v", tt.name, ids(got))
+					}
+				}
+
+			},
+		},
+		{
+			name: "list pages in creation order",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				var want []uuid.UUID
+				created := make([]entities.Subscriptions, 0, 5)
+				for _, name := range []string{"Netflix", "Spotify", "YouTube", "Kinopoisk", "Okko"} {
+					created = append(created, *create(t, ctx, repo, alice, name, 100, entities.StatusActive))
+				}
+				slices.SortFunc(created, func(a, b entities.Subscriptions) int {
+					if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+						return c
+					}
+					return slices.Compare(a.ID[:], b.ID[:])
+				})
+				for _, sub := range created {
+					want = append(want, sub.ID)
+				}
+
+				var got []uuid.UUID
+				for offset := 0; offset < len(want); offset += 2 {
+					page, err := repo.List(ctx, ListOptions{Limit: ptr(2), Offset: ptr(offset)})
+					if err != nil {
+						t.Fatal(err)
+					}
+					if wantLen := min(2, len(want)-offset); len(page) != wantLen {
+						t.Fatalf("page at offset %d = %d subscriptions, want %d", offset, len(page), wantLen)
+					}
+					for _, sub := range page {
+						got = append(got, sub.ID)
+					}
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("pages = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name: "delete and restore",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				kept := create(t, ctx, repo, alice, "Netflix", 100, entities.StatusActive)
+				deleted := create(t, ctx, repo, alice, "Spotify", 200, entities.StatusActive)
+				if err := repo.Delete(ctx, deleted.ID); err != nil {
+					t.Fatal(err)
+				}
+
+				if _, err := repo.GetByID(ctx, deleted.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Errorf("GetByID of a deleted subscription error = %v, want not found", err)
+				}
+				if got, err := repo.List(ctx, ListOptions{}); err != nil || !sameIDs(got, kept) {
+					t.Errorf("List = %v, %v; want only the kept subscription", ids(got), err)
+				}
+				if got, err := repo.ListDeleted(ctx, ListOptions{}); err != nil || !sameIDs(got, deleted) {
+					t.Errorf("ListDeleted = %v, %v; want the deleted subscription", ids(got), err)
+				}
+
+				restored, err := repo.Restore(ctx, deleted.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if restored.ID != deleted.ID {
+					t.Errorf("Restore = %v, want %v", restored.ID, deleted.ID)
+				}
+				if got, err := repo.List(ctx, ListOptions{}); err != nil || !sameIDs(got, kept, deleted) {
+					t.Errorf("List after restore = %v, %v", ids(got), err)
+				}
+				if _, err := repo.Restore(ctx, kept.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Errorf("Restore of a live subscription error = %v, want not found", err)
+				}
+			},
+		},
+		{
+			name: "as of an earlier time",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				sub := create(t, ctx, repo, alice, "Netflix", 100, entities.StatusActive)
+				time.Sleep(10 * time.Millisecond)
+				before := time.Now().UTC()
+				time.Sleep(10 * time.Millisecond)
+
+				sub.Price = 250
+				if err := repo.Update(ctx, sub); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := repo.GetByIDAsOf(ctx, sub.ID, before)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !near(got.Price, 100) {
+					t.Errorf("GetByIDAsOf price = %v, want 100", got.Price)
+				}
+				listed, err := repo.List(ctx, ListOptions{AsOf: &before})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(listed) != 1 || !near(listed[0].Price, 100) {
+					t.Errorf("List as of = %+v, want the price of 100", listed)
+				}
+			},
+		},
+		{
+			name: "summary",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				netflix := create(t, ctx, repo, alice, "Netflix", 100, entities.StatusActive)
+				spotify := create(t, ctx, repo, bob, "Spotify", 200, entities.StatusActive)
+				late := &entities.Subscriptions{
+					ServiceName: "YouTube",
+					Price:       300,
+					UserID:      alice,
+					StartDate:   month(t, "06-2026"),
+					Status:      entities.StatusActive,
+				}
+				if err := repo.Create(ctx, late); err != nil {
+					t.Fatal(err)
+				}
+				if err := repo.SetMember(ctx, &entities.SubscriptionMember{
+					SubscriptionID: spotify.ID,
+					UserID:         carol,
+					Split:          entities.SplitEqual,
+				}); err != nil {
+					t.Fatal(err)
+				}
+
+				netflix.Price = 150
+				if err := repo.SetPrice(ctx, netflix, ptr(month(t, "03-2026"))); err != nil {
+					t.Fatal(err)
+				}
+
+				tests := []struct {
+					name   string
+					filter SummaryOptions
+					want   []uuid.UUID
+				}{
+					{"period", SummaryOptions{}, []uuid.UUID{netflix.ID, spotify.ID}},
+					{"service", SummaryOptions{ServiceName: "Netflix"}, []uuid.UUID{netflix.ID}},
+					{"owner", SummaryOptions{UserID: &alice}, []uuid.UUID{netflix.ID}},
+					{"member", SummaryOptions{UserID: &carol}, []uuid.UUID{spotify.ID}},
+				}
+				for _, tt := range tests {
+					filter := tt.filter
+					filter.StartDate = month(t, "01-2026")
+					filter.EndDate = month(t, "04-2026")
+					got, err := repo.SummarySubscriptions(ctx, filter)
+					if err != nil {
+						t.Fatalf("%s: %v", tt.name, err)
+					}
+					var gotIDs []uuid.UUID
+					for _, sub := range got {
+						gotIDs = append(gotIDs, sub.ID)
+					}
+					if !slices.Equal(sortIDs(gotIDs), sortIDs(tt.want)) {
+						t.Errorf("%s: SummarySubscriptions = %v, want %v", tt.name, gotIDs, tt.want)
+					}
+				}
+
+				got, err := repo.SummarySubscriptions(ctx, SummaryOptions{
+					ServiceName: "Netflix",
+					StartDate:   month(t, "01-2026"),
+					EndDate:     month(t, "04-2026"),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 1 {
+					t.Fatalf("SummarySubscriptions = %d subscriptions, want 1", len(got))
+				}
+				for m, want := range map[string]float64{"02-2026": 100, "03-2026": 150, "04-2026": 150} {
+					if price := got[0].PriceAt(month(t, m)); !near(price, want) {
+						t.Errorf("price in %s = %v, want %v", m, price, want)
+					}
+				}
+				if len(got[0].Members) != 0 {
+					t.Errorf("members = %+v, want none", got[0].Members)
+				}
+			},
+		},
+		{
+			name: "purge keeps the history",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				sub := create(t, ctx, repo, alice, "Netflix", 100, entities.StatusActive)
+				time.Sleep(10 * time.Millisecond)
+				live := time.Now().UTC()
+				time.Sleep(10 * time.Millisecond)
+				if err := repo.Delete(ctx, sub.ID); err != nil {
+					t.Fatal(err)
+				}
+
+				purged, err := repo.Purge(ctx, time.Now().UTC().Add(time.Second))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if purged != 1 {
+					t.Errorf("Purge = %d, want 1", purged)
+				}
+				if deleted, err := repo.ListDeleted(ctx, ListOptions{}); err != nil || len(deleted) != 0 {
+					t.Errorf("ListDeleted after purge = %d subscriptions, %v; want none", len(deleted), err)
+				}
+				got, err := repo.GetByIDAsOf(ctx, sub.ID, live)
+				if err != nil {
+					t.Fatalf("GetByIDAsOf before the deletion: %v", err)
+				}
+				if !near(got.Price, 100) {
+					t.Errorf("GetByIDAsOf price = %v, want 100", got.Price)
+				}
+			},
+		},
+		{
+			name: "a failed transaction rolls back its writes",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				kept := create(t, ctx, repo, alice, "Netflix", 100, entities.StatusActive)
+				failed := errors.New("audit write failed")
+
+				err := repo.Transaction(ctx, func(ctx context.Context) error {
+					create(t, ctx, repo, bob, "Spotify", 200, entities.StatusActive)
+					changed := *kept
+					changed.Price = 150
+					if err := repo.Update(ctx, &changed); err != nil {
+						return err
+					}
+					if err := repo.SetPrice(ctx, &changed, ptr(month(t, "03-2026"))); err != nil {
+						return err
+					}
+					if err := repo.SetMember(ctx, &entities.SubscriptionMember{
+						SubscriptionID: kept.ID,
+						UserID:         carol,
+						Split:          entities.SplitEqual,
+					}); err != nil {
+						return err
+					}
+					return failed
+				})
+				if !errors.Is(err, failed) {
+					t.Fatalf("Transaction error = %v, want %v", err, failed)
+				}
+
+				if got, err := repo.List(ctx, ListOptions{}); err != nil || !sameIDs(got, kept) {
+					t.Errorf("List = %v, %v; want only the subscription created before", ids(got), err)
+				}
+				got, err := repo.SummarySubscriptions(ctx, SummaryOptions{
+					StartDate: month(t, "01-2026"),
+					EndDate:   month(t, "12-2026"),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != 1 || !near(got[0].Price, 100) || !near(got[0].PriceAt(month(t, "06-2026")), 100) || len(got[0].Members) != 0 {
+					t.Errorf("SummarySubscriptions = %+v, want the subscription unchanged", got)
+				}
+			},
+		},
+		{
+			name: "stats list the tenants with billable subscriptions",
+			run: func(t *testing.T, ctx context.Context, repo SubscriptionRepository) {
+				tenantC := uuid.MustParse("00000000-0000-4000-8000-0000000000c1")
+				create(t, ctx, repo, alice, "Netflix", 100, entities.StatusActive)
+				create(t, ctx, repo, alice, "Spotify", 200, entities.StatusActive)
+				create(t, ctx, repo, alice, "YouTube", 300, entities.StatusPaused)
+				create(t, ctx, repo, alice, "Okko", 400, entities.StatusTrial)
+				create(t, tenant.WithID(context.Background(), tenantB), repo, bob, "Netflix", 100, entities.StatusActive)
+				create(t, tenant.WithID(context.Background(), tenantC), repo, carol, "Netflix", 100, entities.StatusTrial)
+
+				stats, err := repo.Stats(ctx, month(t, "03-2026"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				active := map[uuid.UUID]int64{}
+				for _, s := range stats {
+					active[s.TenantID] = s.Active
+				}
+				want := map[uuid.UUID]int64{tenantA: 2, tenantB: 1, tenantC: 0}
+				if !maps.Equal(active, want) {
+					t.Errorf("Stats = %+v, want active counts %v", stats, want)
+				}
+
+				stats, err = repo.Stats(ctx, month(t, "01-2027"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(stats) != 0 {
+					t.Errorf("Stats after the end date = %+v, want none", stats)
+				}
+			},
+		},
+	}
+
+	for name, newRepo := range repos() {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					tt.run(t, tenant.WithID(context.Background(), tenantA), newRepo(t))
+				})
+			}
+		})
+	}
+}
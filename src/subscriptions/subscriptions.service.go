@@ -2,23 +2,85 @@ package subscriptions
 
 import (
 	"context"
+	"effective_mobile/src/_core/actor"
+	"effective_mobile/src/_core/logger"
+	"effective_mobile/src/_core/metrics"
+	"effective_mobile/src/_core/tenant"
+	"effective_mobile/src/_core/tracing"
 	entities "effective_mobile/src/_entities"
+	"effective_mobile/src/audit"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
 )
 
+// ErrInvalidChange is returned when a scheduled change does not fit the
+// subscription.
+var ErrInvalidChange = errors.New("invalid scheduled change")
+
+// ErrInvalidPriceChange is returned when the month a new price takes effect
+// from does not fit the subscription.
+var ErrInvalidPriceChange = errors.New("invalid price change")
+
+// ErrInvalidTrial is returned when a trial does not fit the subscription.
+var ErrInvalidTrial = errors.New("invalid trial")
+
+// ErrInvalidDiscount is returned when a discount does not fit the
+// subscription.
+var ErrInvalidDiscount = errors.New("invalid discount")
+
+// ErrInvalidMember is returned when a member or their share does not fit
+// the subscription.
+var ErrInvalidMember = errors.New("invalid member")
+
+// ErrMemberExists is returned when adding a user who already shares the
+// subscription.
+var ErrMemberExists = errors.New("member already exists")
+
+// ErrInvalidTransition is returned when a subscription cannot move to the
+// requested status from its current one.
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// transitions lists the statuses each status can move to. Cancelled and
+// expired subscriptions stay that way.
+var transitions = map[string][]string{
+	entities.StatusTrial:  {entities.StatusActive, entities.StatusCancelled, entities.StatusExpired},
+	entities.StatusActive: {entities.StatusPaused, entities.StatusCancelled, entities.StatusExpired},
+	entities.StatusPaused: {entities.StatusActive, entities.StatusCancelled, entities.StatusExpired},
+}
+
+// schedulerActor is recorded as the actor of changes applied by the
+// background job.
+const schedulerActor = "scheduler"
+
+// AuditRecorder stores a change made to an entity, with the state before
+// and after it; before is nil on create and after is nil on delete.
+// RecordChild does the same for an entity that belongs to a subscription.
+type AuditRecorder interface {
+	Record(ctx context.Context, entityType, action string, entityID uuid.UUID, before, after any) error
+	RecordChild(ctx context.Context, subscriptionID uuid.UUID, entityType, action string, entityID uuid.UUID, before, after any) error
+}
+
 type SubscriptionService struct {
-	repo *SubscriptionRepo
+	repo  SubscriptionRepository
+	audit AuditRecorder
 }
 
-func NewSubscriptionService(repo *SubscriptionRepo) *SubscriptionService {
-	return &SubscriptionService{repo: repo}
+func NewSubscriptionService(repo SubscriptionRepository, audit AuditRecorder) *SubscriptionService {
+	return &SubscriptionService{repo: repo, audit: audit}
 }
 
-func (s *SubscriptionService) Create(ctx context.Context, data CreateSubscription) (*ResSubscription, error) {
+func (s *SubscriptionService) Create(ctx context.Context, data CreateSubscription) (_ *ResSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.Create")
+	defer func() { tracing.End(span, err) }()
+
 	userID, err := uuid.Parse(data.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid user ID: %v", err)
@@ -38,82 +100,236 @@ func (s *SubscriptionService) Create(ctx context.Context, data CreateSubscriptio
 		endDate = &ed
 	}
 
+	status := data.Status
+	if status == "" {
+		status = entities.StatusActive
+	}
+
 	sub := entities.Subscriptions{
 		ServiceName: data.ServiceName,
 		Price:       data.Price,
 		UserID:      userID,
 		StartDate:   startDate,
 		EndDate:     endDate,
+		Status:      status,
+	}
+	if data.TrialEnd != "" || status == entities.StatusTrial {
+		if err := setTrial(&sub, data); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.repo.Create(ctx, &sub); err != nil {
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, &sub); err != nil {
+			return err
+		}
+		if sub.TrialEnd != nil {
+			if err := s.recordTrial(ctx, &sub); err != nil {
+				return err
+			}
+		}
+		return s.audit.Record(ctx, audit.EntitySubscription, audit.ActionCreate, sub.ID, nil, sub)
+	})
+	if err != nil {
 		return nil, err
 	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription created", slog.String("subscription_id", sub.ID.String()))
 
-	response := &ResSubscription{
-		ID:          sub.ID,
-		ServiceName: sub.ServiceName,
-		Price:       sub.Price,
-		UserID:      sub.UserID,
-		StartDate:   formatMonthYear(sub.StartDate),
+	return convertToResponse(&sub), nil
+}
+
+// setTrial sets the trial of a new subscription from data. The trial must
+// fit within the subscription; months before it are billed in full.
+func setTrial(sub *entities.Subscriptions, data CreateSubscription) error {
+	if sub.Status != entities.StatusTrial && data.Status != "" {
+		return fmt.Errorf("%w: a subscription with a trial must start in the trial status", ErrInvalidTrial)
 	}
 
-	if sub.EndDate != nil {
-		endDateStr := formatMonthYear(*sub.EndDate)
-		response.EndDate = &endDateStr
+	trialStart := sub.StartDate
+	if data.TrialStart != "" {
+		start, err := parseMonthYear(data.TrialStart)
+		if err != nil {
+			return fmt.Errorf("%w: invalid trial start: %v", ErrInvalidTrial, err)
+		}
+		trialStart = start
+	}
+	if data.TrialEnd == "" {
+		return fmt.Errorf("%w: a trial needs a trial end", ErrInvalidTrial)
+	}
+	trialEnd, err := parseMonthYear(data.TrialEnd)
+	if err != nil {
+		return fmt.Errorf("%w: invalid trial end: %v", ErrInvalidTrial, err)
+	}
+
+	switch {
+	case trialStart.Before(sub.StartDate):
+		return fmt.Errorf("%w: trial starts before the subscription", ErrInvalidTrial)
+	case trialEnd.Before(trialStart):
+		return fmt.Errorf("%w: trial ends before it starts", ErrInvalidTrial)
+	case sub.EndDate != nil && trialEnd.After(*sub.EndDate):
+		return fmt.Errorf("%w: trial ends after the subscription", ErrInvalidTrial)
 	}
 
-	return response, nil
+	sub.Status = entities.StatusTrial
+	sub.TrialStart = &trialStart
+	sub.TrialEnd = &trialEnd
+	sub.TrialPrice = data.TrialPrice
+	if data.CancelAfterTrial {
+		cancelAt := trialEnd.AddDate(0, 1, 0)
+		sub.CancelAt = &cancelAt
+	}
+	return nil
 }
 
-func (s *SubscriptionService) GetByID(ctx context.Context, id uuid.UUID) (*ResSubscription, error) {
-	sub, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		return nil, err
+// recordTrial records the statuses of a new subscription with a trial: active
+// until the trial starts, trial during it, and then active or, when
+// cancelled after the trial, cancelled.
+func (s *SubscriptionService) recordTrial(ctx context.Context, sub *entities.Subscriptions) error {
+	if sub.TrialStart.After(sub.StartDate) {
+		if err := s.repo.SetStatus(ctx, sub.ID, entities.StatusActive, sub.StartDate); err != nil {
+			return err
+		}
+		if err := s.repo.SetStatus(ctx, sub.ID, entities.StatusTrial, *sub.TrialStart); err != nil {
+			return err
+		}
 	}
 
-	return convertToResponse(sub), nil
+	converted := entities.StatusActive
+	if sub.CancelAt != nil {
+		converted = entities.StatusCancelled
+	}
+	return s.repo.SetStatus(ctx, sub.ID, converted, sub.TrialEnd.AddDate(0, 1, 0))
 }
 
-func (s *SubscriptionService) Update(ctx context.Context, id uuid.UUID, data UpdateSubscription) (*ResSubscription, error) {
-	sub, err := s.repo.GetByID(ctx, id)
+// GetByID returns the subscription, or its state at asOf when set.
+func (s *SubscriptionService) GetByID(ctx context.Context, id uuid.UUID, asOf *time.Time) (_ *ResSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.GetByID", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var sub *entities.Subscriptions
+	if asOf != nil {
+		sub, err = s.repo.GetByIDAsOf(ctx, id, *asOf)
+	} else {
+		sub, err = s.repo.GetByID(ctx, id)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if data.ServiceName != nil {
-		sub.ServiceName = *data.ServiceName
+	result, err := s.responses(ctx, []entities.Subscriptions{*sub}, asOf)
+	if err != nil {
+		return nil, err
 	}
-	if data.Price != nil {
-		sub.Price = *data.Price
+
+	return &result[0], nil
+}
+
+func (s *SubscriptionService) Update(ctx context.Context, id uuid.UUID, data UpdateSubscription) (_ *ResSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.Update", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var startDate, endDate time.Time
+	var priceFrom *time.Time
+	if data.PriceEffectiveFrom != nil {
+		from, err := parseMonthYear(*data.PriceEffectiveFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price effective month: %v", err)
+		}
+		priceFrom = &from
 	}
 	if data.StartDate != nil {
-		startDate, err := parseMonthYear(*data.StartDate)
-		if err != nil {
+		if startDate, err = parseMonthYear(*data.StartDate); err != nil {
 			return nil, fmt.Errorf("invalid start date: %v", err)
 		}
-		sub.StartDate = startDate
 	}
 	if data.EndDate != nil {
-		endDate, err := parseMonthYear(*data.EndDate)
-		if err != nil {
+		if endDate, err = parseMonthYear(*data.EndDate); err != nil {
 			return nil, fmt.Errorf("invalid end date: %v", err)
 		}
-		sub.EndDate = &endDate
 	}
 
-	if err := s.repo.Update(ctx, sub); err != nil {
+	var sub *entities.Subscriptions
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		// The row is written back whole, so it must not come from a lagging replica.
+		sub, err = s.repo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		before := *sub
+
+		if data.ServiceName != nil {
+			sub.ServiceName = *data.ServiceName
+		}
+		if data.Price != nil {
+			sub.Price = *data.Price
+		}
+		if data.StartDate != nil {
+			sub.StartDate = startDate
+		}
+		if data.EndDate != nil {
+			sub.EndDate = &endDate
+		}
+
+		if data.Price != nil {
+			if err := validatePriceChange(sub, priceFrom); err != nil {
+				return err
+			}
+		}
+
+		if err := s.repo.Update(ctx, sub); err != nil {
+			return err
+		}
+		if data.Price != nil {
+			if err := s.repo.SetPrice(ctx, sub, priceFrom); err != nil {
+				return err
+			}
+		}
+		return s.audit.Record(ctx, audit.EntitySubscription, audit.ActionUpdate, sub.ID, before, *sub)
+	})
+	if err != nil {
 		return nil, err
 	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription updated", slog.String("subscription_id", sub.ID.String()))
 
-	return convertToResponse(sub), nil
+	result, err := s.responses(ctx, []entities.Subscriptions{*sub}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result[0], nil
 }
 
-func (s *SubscriptionService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id)
+func (s *SubscriptionService) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.Delete", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		sub, err := s.repo.GetForUpdate(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Deleting is idempotent; there is nothing to record.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, audit.EntitySubscription, audit.ActionDelete, id, *sub, nil)
+	})
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription deleted", slog.String("subscription_id", id.String()))
+
+	return nil
 }
 
-func (s *SubscriptionService) List(ctx context.Context, filter SubscriptionList) ([]ResSubscription, error) {
+func (s *SubscriptionService) List(ctx context.Context, filter SubscriptionList) (_ []ResSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.List")
+	defer func() { tracing.End(span, err) }()
+
 	options, err := s.parseListOptions(filter)
 	if err != nil {
 		return nil, err
@@ -124,21 +340,740 @@ func (s *SubscriptionService) List(ctx context.Context, filter SubscriptionList)
 		return nil, err
 	}
 
-	result := make([]ResSubscription, len(subs))
+	return s.responses(ctx, subs, options.AsOf)
+}
+
+// Trash lists soft-deleted subscriptions, most recently deleted first.
+func (s *SubscriptionService) Trash(ctx context.Context, filter SubscriptionList) (_ []ResSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.Trash")
+	defer func() { tracing.End(span, err) }()
+
+	options, err := s.parseListOptions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := s.repo.ListDeleted(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.responses(ctx, subs, nil)
+}
+
+func (s *SubscriptionService) Restore(ctx context.Context, id uuid.UUID) (_ *ResSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.Restore", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var sub *entities.Subscriptions
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		sub, err = s.repo.Restore(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, audit.EntitySubscription, audit.ActionRestore, id, nil, *sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription restored", slog.String("subscription_id", id.String()))
+
+	result, err := s.responses(ctx, []entities.Subscriptions{*sub}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result[0], nil
+}
+
+// ScheduleChange schedules a price or plan change for a future month. A
+// change already pending for that month is replaced.
+func (s *SubscriptionService) ScheduleChange(ctx context.Context, id uuid.UUID, data ScheduleChange) (_ *ResScheduledChange, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.ScheduleChange", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	effectiveFrom, err := parseMonthYear(data.EffectiveFrom)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid effective month: %v", ErrInvalidChange, err)
+	}
+
+	change := entities.SubscriptionChange{
+		SubscriptionID: id,
+		EffectiveFrom:  effectiveFrom,
+		Price:          data.Price,
+		ServiceName:    data.ServiceName,
+	}
+
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		sub, err := s.repo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := validateSchedule(sub, effectiveFrom); err != nil {
+			return err
+		}
+
+		if err := s.repo.ScheduleChange(ctx, &change); err != nil {
+			return err
+		}
+		return s.audit.RecordChild(ctx, id, audit.EntitySubscriptionChange, audit.ActionCreate, change.ID, nil, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription change scheduled",
+		slog.String("subscription_id", id.String()),
+		slog.String("change_id", change.ID.String()),
+	)
+
+	return convertChange(&change), nil
+}
+
+// CancelChange cancels a pending change.
+func (s *SubscriptionService) CancelChange(ctx context.Context, id, changeID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.CancelChange", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		cancelled, err := s.repo.CancelChange(ctx, id, changeID)
+		if err != nil {
+			return err
+		}
+		return s.audit.RecordChild(ctx, id, audit.EntitySubscriptionChange, audit.ActionDelete, changeID, *cancelled, nil)
+	})
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription change cancelled",
+		slog.String("subscription_id", id.String()),
+		slog.String("change_id", changeID.String()),
+	)
+
+	return nil
+}
+
+// ListChanges returns every change scheduled for a subscription, including
+// applied and cancelled ones.
+func (s *SubscriptionService) ListChanges(ctx context.Context, id uuid.UUID) (_ []ResScheduledChange, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.ListChanges", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.ListChanges(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ResScheduledChange, len(changes))
+	for i := range changes {
+		result[i] = *convertChange(&changes[i])
+	}
+
+	return result, nil
+}
+
+// ApplyDueChanges applies the scheduled changes whose month has come, oldest
+// first, and records when each took effect. A change that fails is retried
+// on the next run.
+func (s *SubscriptionService) ApplyDueChanges(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.ApplyDueChanges")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now().UTC()
+	changes, err := s.repo.DueChanges(ctx, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, change := range changes {
+		if err := s.applyChange(ctx, change, now); err != nil {
+			errs = append(errs, fmt.Errorf("change %s: %w", change.ID, err))
+		}
+	}
+	if applied := len(changes) - len(errs); applied > 0 {
+		logger.FromContext(ctx).InfoContext(ctx, "Applied scheduled changes", slog.Int("count", applied))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *SubscriptionService) applyChange(ctx context.Context, change entities.SubscriptionChange, now time.Time) error {
+	ctx = tenant.WithID(ctx, change.TenantID)
+	ctx = actor.WithName(ctx, schedulerActor)
+
+	return s.repo.Transaction(ctx, func(ctx context.Context) error {
+		sub, err := s.repo.GetForUpdate(ctx, change.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		before := *sub
+
+		if change.ServiceName != nil {
+			sub.ServiceName = *change.ServiceName
+		}
+		if change.Price != nil {
+			sub.Price = *change.Price
+		}
+
+		if err := s.repo.Update(ctx, sub); err != nil {
+			return err
+		}
+		if change.Price != nil {
+			if err := s.repo.SetPrice(ctx, sub, &change.EffectiveFrom); err != nil {
+				return err
+			}
+		}
+		if err := s.repo.MarkChangeApplied(ctx, change.ID, now); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, audit.EntitySubscription, audit.ActionUpdate, sub.ID, before, *sub)
+	})
+}
+
+// Pause stops billing the subscription from the current month.
+func (s *SubscriptionService) Pause(ctx context.Context, id uuid.UUID) (_ *ResSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.Pause", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	return s.transition(ctx, id, func(sub *entities.Subscriptions, month time.Time) (string, time.Time, error) {
+		if err := checkTransition(sub, entities.StatusActive, entities.StatusPaused); err != nil {
+			return "", month, err
+		}
+		sub.Status = entities.StatusPaused
+		return sub.Status, month, nil
+	})
+}
+
+// Resume bills a paused subscription again from the current month.
+func (s *SubscriptionService) Resume(ctx context.Context, id uuid.UUID) (_ *ResSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.Resume", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	return s.transition(ctx, id, func(sub *entities.Subscriptions, month time.Time) (string, time.Time, error) {
+		if err := checkTransition(sub, entities.StatusPaused, entities.StatusActive); err != nil {
+			return "", month, err
+		}
+		sub.Status = entities.StatusActive
+		return sub.Status, month, nil
+	})
+}
+
+// Activate ends the trial of a subscription; it is billed from the current
+// month.
+func (s *SubscriptionService) Activate(ctx context.Context, id uuid.UUID) (_ *ResSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.Activate", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	return s.transition(ctx, id, func(sub *entities.Subscriptions, month time.Time) (string, time.Time, error) {
+		if err := checkTransition(sub, entities.StatusTrial, entities.StatusActive); err != nil {
+			return "", month, err
+		}
+		sub.Status = entities.StatusActive
+		return sub.Status, month, nil
+	})
+}
+
+// Cancel cancels the subscription from the current month, or from the next
+// one when data.AtPeriodEnd is set. The current month is billed in the
+// latter case only.
+func (s *SubscriptionService) Cancel(ctx context.Context, id uuid.UUID, data CancelSubscription) (_ *ResSubscription, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.Cancel", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var reason *string
+	if data.Reason != "" {
+		reason = &data.Reason
+	}
+
+	return s.transition(ctx, id, func(sub *entities.Subscriptions, month time.Time) (string, time.Time, error) {
+		if !canTransition(sub.Status, entities.StatusCancelled) {
+			return "", month, fmt.Errorf("%w: cannot cancel a %s subscription", ErrInvalidTransition, sub.Status)
+		}
+		sub.CancellationReason = reason
+
+		if data.AtPeriodEnd {
+			if sub.CancelAt != nil {
+				return "", month, fmt.Errorf("%w: a cancellation is already pending", ErrInvalidTransition)
+			}
+			next := month.AddDate(0, 1, 0)
+			sub.CancelAt = &next
+			return entities.StatusCancelled, next, nil
+		}
+
+		now := time.Now().UTC()
+		sub.Status = entities.StatusCancelled
+		sub.CancelAt = &month
+		sub.CancelledAt = &now
+		return sub.Status, month, nil
+	})
+}
+
+// transition applies a status change to the subscription. apply updates sub
+// and returns the status to record and the month it takes effect. The row is
+// locked until the change is stored, and subscriptions that ended before the
+// current month cannot change status any more.
+func (s *SubscriptionService) transition(
+	ctx context.Context,
+	id uuid.UUID,
+	apply func(sub *entities.Subscriptions, month time.Time) (string, time.Time, error),
+) (*ResSubscription, error) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var sub *entities.Subscriptions
+	err := s.repo.Transaction(ctx, func(ctx context.Context) (err error) {
+		sub, err = s.repo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if sub.EndDate != nil && sub.EndDate.Before(month) {
+			return fmt.Errorf("%w: subscription ended in %s", ErrInvalidTransition, formatMonthYear(*sub.EndDate))
+		}
+		before := *sub
+
+		status, from, err := apply(sub, month)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.Update(ctx, sub); err != nil {
+			return err
+		}
+		if err := s.repo.SetStatus(ctx, sub.ID, status, from); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, audit.EntitySubscription, audit.ActionUpdate, sub.ID, before, *sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription status changed",
+		slog.String("subscription_id", sub.ID.String()),
+		slog.String("status", sub.Status),
+	)
+
+	result, err := s.responses(ctx, []entities.Subscriptions{*sub}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result[0], nil
+}
+
+// AdvanceLifecycle cancels subscriptions whose cancellation month has come,
+// converts ended trials to paid and expires subscriptions past their end
+// date. A subscription that fails is
+// retried on the next run.
+func (s *SubscriptionService) AdvanceLifecycle(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.AdvanceLifecycle")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	subs, err := s.repo.LifecycleDue(ctx, month)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sub := range subs {
+		if err := s.advance(ctx, sub, month, now); err != nil {
+			errs = append(errs, fmt.Errorf("subscription %s: %w", sub.ID, err))
+		}
+	}
+	if advanced := len(subs) - len(errs); advanced > 0 {
+		logger.FromContext(ctx).InfoContext(ctx, "Advanced subscription lifecycle", slog.Int("count", advanced))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *SubscriptionService) advance(ctx context.Context, due entities.Subscriptions, month, now time.Time) error {
+	ctx = tenant.WithID(ctx, due.TenantID)
+	ctx = actor.WithName(ctx, schedulerActor)
+
+	return s.repo.Transaction(ctx, func(ctx context.Context) error {
+		sub, err := s.repo.GetForUpdate(ctx, due.ID)
+		if err != nil {
+			return err
+		}
+		before := *sub
+
+		switch {
+		case !canTransition(sub.Status, entities.StatusCancelled):
+			return nil
+		case sub.CancelAt != nil && !sub.CancelAt.After(month):
+			// The status entry was recorded when the cancellation was requested.
+			sub.Status = entities.StatusCancelled
+			sub.CancelledAt = &now
+		case sub.Status == entities.StatusTrial && sub.TrialEnd != nil && sub.TrialEnd.Before(month):
+			// Likewise, the conversion was recorded with the trial.
+			sub.Status = entities.StatusActive
+		case sub.EndDate != nil && sub.EndDate.Before(month):
+			sub.Status = entities.StatusExpired
+			if err := s.repo.SetStatus(ctx, sub.ID, sub.Status, sub.EndDate.AddDate(0, 1, 0)); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+
+		if err := s.repo.Update(ctx, sub); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, audit.EntitySubscription, audit.ActionUpdate, sub.ID, before, *sub)
+	})
+}
+
+// AddDiscount attaches a discount to the subscription.
+func (s *SubscriptionService) AddDiscount(ctx context.Context, id uuid.UUID, data AddDiscount) (_ *ResDiscount, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.AddDiscount", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	discount := entities.SubscriptionDiscount{
+		SubscriptionID: id,
+		Kind:           data.Kind,
+		Amount:         data.Amount,
+		Cycles:         data.Cycles,
+		Description:    data.Description,
+	}
+	if discount.StartDate, err = parseMonthYear(data.StartDate); err != nil {
+		return nil, fmt.Errorf("%w: invalid start date: %v", ErrInvalidDiscount, err)
+	}
+	if data.EndDate != nil {
+		endDate, err := parseMonthYear(*data.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid end date: %v", ErrInvalidDiscount, err)
+		}
+		discount.EndDate = &endDate
+	}
+
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		sub, err := s.repo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := validateDiscount(sub, &discount); err != nil {
+			return err
+		}
+
+		if err := s.repo.AddDiscount(ctx, &discount); err != nil {
+			return err
+		}
+		return s.audit.RecordChild(ctx, id, audit.EntitySubscriptionDiscount, audit.ActionCreate, discount.ID, nil, discount)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription discount added",
+		slog.String("subscription_id", id.String()),
+		slog.String("discount_id", discount.ID.String()),
+	)
+
+	return convertDiscount(&discount), nil
+}
+
+// RemoveDiscount removes a discount from the subscription. Summaries as of
+// an earlier time still apply it.
+func (s *SubscriptionService) RemoveDiscount(ctx context.Context, id, discountID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.RemoveDiscount", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		removed, err := s.repo.RemoveDiscount(ctx, id, discountID)
+		if err != nil {
+			return err
+		}
+		return s.audit.RecordChild(ctx, id, audit.EntitySubscriptionDiscount, audit.ActionDelete, discountID, *removed, nil)
+	})
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription discount removed",
+		slog.String("subscription_id", id.String()),
+		slog.String("discount_id", discountID.String()),
+	)
+
+	return nil
+}
+
+// ListDiscounts returns the current discounts of a subscription.
+func (s *SubscriptionService) ListDiscounts(ctx context.Context, id uuid.UUID) (_ []ResDiscount, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.ListDiscounts", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	discounts, err := s.repo.ListDiscounts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ResDiscount, len(discounts))
+	for i := range discounts {
+		result[i] = *convertDiscount(&discounts[i])
+	}
+
+	return result, nil
+}
+
+// AddMember shares the subscription with another user.
+func (s *SubscriptionService) AddMember(ctx context.Context, id uuid.UUID, data AddMember) (_ *ResMember, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.AddMember", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	userID, err := uuid.Parse(data.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user ID: %v", ErrInvalidMember, err)
+	}
+
+	member := entities.SubscriptionMember{
+		SubscriptionID: id,
+		UserID:         userID,
+		Split:          data.Split,
+		Share:          data.Share,
+	}
+
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		current, err := s.members(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, ok := current.byUser[userID]; ok {
+			return ErrMemberExists
+		}
+		if err := validateMember(current.sub, current.members, &member); err != nil {
+			return err
+		}
+
+		if err := s.repo.SetMember(ctx, &member); err != nil {
+			return err
+		}
+		return s.audit.RecordChild(ctx, id, audit.EntitySubscriptionMember, audit.ActionCreate, userID, nil, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription member added",
+		slog.String("subscription_id", id.String()),
+		slog.String("user_id", userID.String()),
+	)
+
+	return convertMember(&member), nil
+}
+
+// UpdateMember changes how a member's share is computed.
+func (s *SubscriptionService) UpdateMember(ctx context.Context, id, userID uuid.UUID, data UpdateMember) (_ *ResMember, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.UpdateMember", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	member := entities.SubscriptionMember{
+		SubscriptionID: id,
+		UserID:         userID,
+		Split:          data.Split,
+		Share:          data.Share,
+	}
+
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		current, err := s.members(ctx, id)
+		if err != nil {
+			return err
+		}
+		before, ok := current.byUser[userID]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		if err := validateMember(current.sub, current.members, &member); err != nil {
+			return err
+		}
+
+		if err := s.repo.SetMember(ctx, &member); err != nil {
+			return err
+		}
+		return s.audit.RecordChild(ctx, id, audit.EntitySubscriptionMember, audit.ActionUpdate, userID, before, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription member updated",
+		slog.String("subscription_id", id.String()),
+		slog.String("user_id", userID.String()),
+	)
+
+	return convertMember(&member), nil
+}
+
+// RemoveMember stops sharing the subscription with a user. Summaries as of
+// an earlier time still count their share.
+func (s *SubscriptionService) RemoveMember(ctx context.Context, id, userID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.RemoveMember", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	err = s.repo.Transaction(ctx, func(ctx context.Context) error {
+		removed, err := s.repo.RemoveMember(ctx, id, userID)
+		if err != nil {
+			return err
+		}
+		return s.audit.RecordChild(ctx, id, audit.EntitySubscriptionMember, audit.ActionDelete, userID, *removed, nil)
+	})
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).InfoContext(ctx, "Subscription member removed",
+		slog.String("subscription_id", id.String()),
+		slog.String("user_id", userID.String()),
+	)
+
+	return nil
+}
+
+// ListMembers returns the users sharing a subscription, besides its owner.
+func (s *SubscriptionService) ListMembers(ctx context.Context, id uuid.UUID) (_ []ResMember, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.ListMembers", attribute.String("subscription.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.ListMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ResMember, len(members))
+	for i := range members {
+		result[i] = *convertMember(&members[i])
+	}
+
+	return result, nil
+}
+
+// membership is a subscription with its current members.
+type membership struct {
+	sub     *entities.Subscriptions
+	members []entities.SubscriptionMember
+	byUser  map[uuid.UUID]entities.SubscriptionMember
+}
+
+// members loads the subscription and its members for a change to them. It
+// runs inside a transaction, so the members are read from the primary too.
+func (s *SubscriptionService) members(ctx context.Context, id uuid.UUID) (*membership, error) {
+	sub, err := s.repo.GetForUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.repo.ListMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[uuid.UUID]entities.SubscriptionMember, len(members))
+	for _, member := range members {
+		byUser[member.UserID] = member
+	}
+	return &membership{sub: sub, members: members, byUser: byUser}, nil
+}
+
+// PurgeDeleted permanently removes subscriptions that have been in the trash
+// for longer than retention.
+func (s *SubscriptionService) PurgeDeleted(ctx context.Context, retention time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.PurgeDeleted")
+	defer func() { tracing.End(span, err) }()
+
+	purged, err := s.repo.Purge(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		logger.FromContext(ctx).InfoContext(ctx, "Purged deleted subscriptions", slog.Int64("count", purged))
+	}
+
+	return nil
+}
+
+// responses converts subs and adds the changes pending for them at asOf, or
+// now when asOf is nil.
+func (s *SubscriptionService) responses(ctx context.Context, subs []entities.Subscriptions, asOf *time.Time) ([]ResSubscription, error) {
+	ids := make([]uuid.UUID, len(subs))
 	for i, sub := range subs {
-		result[i] = *convertToResponse(&sub)
+		ids[i] = sub.ID
+	}
+
+	pending, err := s.repo.PendingChanges(ctx, ids, asOf)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID][]ResScheduledChange)
+	for i := range pending {
+		byID[pending[i].SubscriptionID] = append(byID[pending[i].SubscriptionID], *convertChange(&pending[i]))
+	}
+
+	result := make([]ResSubscription, len(subs))
+	for i := range subs {
+		result[i] = *convertToResponse(&subs[i])
+		result[i].PendingChanges = byID[subs[i].ID]
 	}
 
 	return result, nil
 }
 
+func convertChange(change *entities.SubscriptionChange) *ResScheduledChange {
+	return &ResScheduledChange{
+		ID:            change.ID,
+		EffectiveFrom: formatMonthYear(change.EffectiveFrom),
+		Price:         change.Price,
+		ServiceName:   change.ServiceName,
+		CreatedAt:     change.CreatedAt,
+		AppliedAt:     change.AppliedAt,
+		CancelledAt:   change.CancelledAt,
+	}
+}
+
+func convertMember(member *entities.SubscriptionMember) *ResMember {
+	return &ResMember{
+		UserID:    member.UserID,
+		Split:     member.Split,
+		Share:     member.Share,
+		UpdatedAt: member.ValidFrom,
+	}
+}
+
+func convertDiscount(discount *entities.SubscriptionDiscount) *ResDiscount {
+	response := &ResDiscount{
+		ID:          discount.ID,
+		Kind:        discount.Kind,
+		Amount:      discount.Amount,
+		StartDate:   formatMonthYear(discount.StartDate),
+		Cycles:      discount.Cycles,
+		Description: discount.Description,
+		CreatedAt:   discount.ValidFrom,
+	}
+
+	if discount.EndDate != nil {
+		endDate := formatMonthYear(*discount.EndDate)
+		response.EndDate = &endDate
+	}
+
+	return response
+}
+
 func convertToResponse(sub *entities.Subscriptions) *ResSubscription {
 	response := &ResSubscription{
-		ID:          sub.ID,
-		ServiceName: sub.ServiceName,
-		Price:       sub.Price,
-		UserID:      sub.UserID,
-		StartDate:   formatMonthYear(sub.StartDate),
+		ID:                 sub.ID,
+		ServiceName:        sub.ServiceName,
+		Price:              sub.Price,
+		UserID:             sub.UserID,
+		StartDate:          formatMonthYear(sub.StartDate),
+		Status:             sub.Status,
+		CancelledAt:        sub.CancelledAt,
+		CancellationReason: sub.CancellationReason,
 	}
 
 	if sub.EndDate != nil {
@@ -146,6 +1081,24 @@ func convertToResponse(sub *entities.Subscriptions) *ResSubscription {
 		response.EndDate = &endDateStr
 	}
 
+	if sub.CancelAt != nil {
+		cancelAt := formatMonthYear(*sub.CancelAt)
+		response.CancelAt = &cancelAt
+	}
+
+	if sub.TrialEnd != nil {
+		trialStart := formatMonthYear(*sub.TrialStart)
+		trialEnd := formatMonthYear(*sub.TrialEnd)
+		response.TrialStart = &trialStart
+		response.TrialEnd = &trialEnd
+		response.TrialPrice = sub.TrialPrice
+	}
+
+	if sub.DeletedAt.Valid {
+		deletedAt := sub.DeletedAt.Time
+		response.DeletedAt = &deletedAt
+	}
+
 	return response
 }
 
@@ -179,6 +1132,28 @@ func (s *SubscriptionService) parseListOptions(filter SubscriptionList) (ListOpt
 		options.Offset = &offset
 	}
 
+	options.Status = filter.Status
+
+	if filter.TrialEndingSoon != "" {
+		soon, err := strconv.ParseBool(filter.TrialEndingSoon)
+		if err != nil {
+			return options, fmt.Errorf("trial_ending_soon must be a boolean")
+		}
+		if soon {
+			now := time.Now().UTC()
+			by := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			options.TrialEndsBy = &by
+		}
+	}
+
+	if filter.AsOf != "" {
+		asOf, err := time.Parse(time.RFC3339, filter.AsOf)
+		if err != nil {
+			return options, fmt.Errorf("as_of must be an RFC 3339 timestamp")
+		}
+		options.AsOf = &asOf
+	}
+
 	return options, nil
 }
 
@@ -187,7 +1162,14 @@ func (s *SubscriptionService) GetSubscriptionSummary(
 	userID *uuid.UUID,
 	serviceName string,
 	startDateStr, endDateStr string,
-) (*ResSubscriptionSummary, error) {
+	asOf *time.Time,
+) (_ *ResSubscriptionSummary, err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.GetSubscriptionSummary",
+		attribute.String("summary.start_date", startDateStr),
+		attribute.String("summary.end_date", endDateStr),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	startDate, err := parseMonthYear(startDateStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid start date: %v", err)
@@ -198,25 +1180,282 @@ func (s *SubscriptionService) GetSubscriptionSummary(
 		return nil, fmt.Errorf("invalid end date: %v", err)
 	}
 
-	total, count, err := s.repo.GetSubscriptionSummary(
-		ctx,
-		userID,
-		serviceName,
-		startDate,
-		endDate,
-	)
+	subs, err := s.repo.SummarySubscriptions(ctx, SummaryOptions{
+		UserID:      userID,
+		ServiceName: serviceName,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		AsOf:        asOf,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	var gross, discount, paid float64
+	for _, sub := range subs {
+		cost := periodCost(sub, startDate, endDate, userID)
+		gross += cost.gross
+		discount += cost.discount
+		paid += cost.paid
+	}
+	net := roundPrice(roundPrice(gross) - roundPrice(discount))
+
 	return &ResSubscriptionSummary{
-		TotalPrice: total,
+		TotalPrice: net,
+		GrossPrice: roundPrice(gross),
+		Discount:   roundPrice(discount),
+		NetPrice:   net,
+		PaidPrice:  roundPrice(paid),
 		StartDate:  startDateStr,
 		EndDate:    endDateStr,
-		Count:      count,
+		Count:      len(subs),
 	}, nil
 }
 
+// RefreshMetrics updates the business gauges with the current month figures.
+func (s *SubscriptionService) RefreshMetrics(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "SubscriptionService.RefreshMetrics")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	stats, err := s.repo.Stats(ctx, month)
+	if err != nil {
+		return err
+	}
+
+	spend := make([]float64, len(stats))
+	for i, stat := range stats {
+		if spend[i], err = s.monthlySpend(tenant.WithID(ctx, stat.TenantID), month); err != nil {
+			return err
+		}
+	}
+
+	metrics.ActiveSubscriptions.Reset()
+	metrics.MonthlyRecurringSpend.Reset()
+	for i, stat := range stats {
+		metrics.ActiveSubscriptions.WithLabelValues(stat.TenantID.String()).Set(float64(stat.Active))
+		metrics.MonthlyRecurringSpend.WithLabelValues(stat.TenantID.String()).Set(spend[i])
+	}
+
+	return nil
+}
+
+// monthlySpend is what the tenant in ctx pays in month, billed like the
+// summary: trials at their price, pauses free and discounts applied.
+func (s *SubscriptionService) monthlySpend(ctx context.Context, month time.Time) (float64, error) {
+	subs, err := s.repo.SummarySubscriptions(ctx, SummaryOptions{StartDate: month, EndDate: month})
+	if err != nil {
+		return 0, err
+	}
+
+	var spend float64
+	for _, sub := range subs {
+		spend += periodCost(sub, month, month, nil).paid
+	}
+	return roundPrice(spend), nil
+}
+
+func canTransition(from, to string) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+// checkTransition checks that sub is in the status from and can move to the
+// status to. Pausing and resuming are not allowed while a cancellation is
+// pending.
+func checkTransition(sub *entities.Subscriptions, from, to string) error {
+	switch {
+	case sub.Status != from || !canTransition(from, to):
+		return fmt.Errorf("%w: subscription is %s", ErrInvalidTransition, sub.Status)
+	case sub.CancelAt != nil:
+		return fmt.Errorf("%w: a cancellation is pending", ErrInvalidTransition)
+	}
+	return nil
+}
+
+// validatePriceChange checks that a price change from the month of from
+// falls within the subscription. Changes cannot start after the current
+// month, as the subscription's price is the one in effect now; later ones
+// are scheduled with ScheduleChange.
+func validatePriceChange(sub *entities.Subscriptions, from *time.Time) error {
+	if from == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	switch {
+	case from.Before(sub.StartDate):
+		return fmt.Errorf("%w: price effective month is before the start date", ErrInvalidPriceChange)
+	case sub.EndDate != nil && from.After(*sub.EndDate):
+		return fmt.Errorf("%w: price effective month is after the end date", ErrInvalidPriceChange)
+	case from.After(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)):
+		return fmt.Errorf("%w: price effective month cannot be after the current month, schedule a change instead", ErrInvalidPriceChange)
+	}
+	return nil
+}
+
+// validateSchedule checks that a change scheduled for the month of from is
+// in the future and within the subscription.
+func validateSchedule(sub *entities.Subscriptions, from time.Time) error {
+	now := time.Now().UTC()
+	switch {
+	case !from.After(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)):
+		return fmt.Errorf("%w: effective month must be after the current month", ErrInvalidChange)
+	case from.Before(sub.StartDate):
+		return fmt.Errorf("%w: effective month is before the start date", ErrInvalidChange)
+	case sub.EndDate != nil && from.After(*sub.EndDate):
+		return fmt.Errorf("%w: effective month is after the end date", ErrInvalidChange)
+	}
+	return nil
+}
+
+// validateDiscount checks that discount starts within the subscription and
+// that a percentage does not exceed 100.
+func validateDiscount(sub *entities.Subscriptions, discount *entities.SubscriptionDiscount) error {
+	switch {
+	case discount.Kind == entities.DiscountPercent && discount.Amount > 100:
+		return fmt.Errorf("%w: percentage cannot exceed 100", ErrInvalidDiscount)
+	case discount.StartDate.Before(sub.StartDate):
+		return fmt.Errorf("%w: start date is before the subscription start", ErrInvalidDiscount)
+	case sub.EndDate != nil && discount.StartDate.After(*sub.EndDate):
+		return fmt.Errorf("%w: start date is after the subscription end", ErrInvalidDiscount)
+	case discount.EndDate != nil && discount.EndDate.Before(discount.StartDate):
+		return fmt.Errorf("%w: end date is before the start date", ErrInvalidDiscount)
+	}
+	return nil
+}
+
+// cost is what a subscription costs over a period. gross and discount are
+// the part attributed to a user, or the whole cost without one; paid is the
+// net cost the user pays as the owner.
+type cost struct {
+	gross, discount, paid float64
+}
+
+// validateMember checks that member is not the owner and that the
+// percentages of all members, with member's new share, add up to at most
+// 100.
+func validateMember(sub *entities.Subscriptions, members []entities.SubscriptionMember, member *entities.SubscriptionMember) error {
+	if member.UserID == sub.UserID {
+		return fmt.Errorf("%w: the owner already shares the subscription", ErrInvalidMember)
+	}
+	if member.Split != entities.SplitPercent {
+		return nil
+	}
+
+	percent := *member.Share
+	for _, other := range members {
+		if other.UserID != member.UserID && other.Split == entities.SplitPercent {
+			percent += *other.Share
+		}
+	}
+	if percent > 100 {
+		return fmt.Errorf("%w: percentage shares add up to more than 100", ErrInvalidMember)
+	}
+	return nil
+}
+
+// periodCost bills sub for every month it is active between start and end,
+// inclusive, at the price in effect in that month. Trial months are billed
+// at the trial price; months in which the subscription was paused or
+// cancelled are free. With userID set, only the user's share of each month
+// is attributed to them, see memberShare.
+//
+// Discounts only apply to active months. Several discounts in a month add
+// up, each computed on the month's price, and never exceed it. Discounts
+// limited to a number of cycles count the active months from their start,
+// so the months before the period are walked too.
+func periodCost(sub PricedSubscription, start, end time.Time, userID *uuid.UUID) cost {
+	var total cost
+	if sub.EndDate != nil && sub.EndDate.Before(end) {
+		end = *sub.EndDate
+	}
+
+	cycles := make([]int, len(sub.Discounts))
+	for month := sub.StartDate; !month.After(end); month = month.AddDate(0, 1, 0) {
+		var price, off float64
+		switch sub.StatusAt(month) {
+		case entities.StatusActive:
+			price = sub.PriceAt(month)
+			for i := range sub.Discounts {
+				d := &sub.Discounts[i]
+				if month.Before(d.StartDate) || (d.EndDate != nil && month.After(*d.EndDate)) {
+					continue
+				}
+				if d.Cycles != nil {
+					if cycles[i] >= *d.Cycles {
+						continue
+					}
+					cycles[i]++
+				}
+				off += d.Off(price)
+			}
+			off = min(off, price)
+		case entities.StatusTrial:
+			if sub.TrialPrice != nil {
+				price = *sub.TrialPrice
+			}
+		}
+
+		if month.Before(start) {
+			continue
+		}
+		if userID == nil || *userID == sub.UserID {
+			total.paid += price - off
+		}
+		if userID == nil {
+			total.gross += price
+			total.discount += off
+			continue
+		}
+		// Shares are computed on the net cost, or on the price when the
+		// month is fully discounted.
+		base := price - off
+		if base <= 0 {
+			base = price
+		}
+		if base > 0 {
+			part := memberShare(sub.Members, sub.UserID, *userID, base) / base
+			total.gross += price * part
+			total.discount += off * part
+		}
+	}
+	return total
+}
+
+// memberShare returns the part of amount attributed to userID. Percent
+// members are served first, then fixed ones, each capped at what is left;
+// equal members and the owner split the rest evenly.
+func memberShare(members []entities.SubscriptionMember, owner, userID uuid.UUID, amount float64) float64 {
+	left := amount
+	shares := make(map[uuid.UUID]float64, len(members)+1)
+	for _, split := range []string{entities.SplitPercent, entities.SplitFixed} {
+		for _, member := range members {
+			if member.Split != split || member.Share == nil {
+				continue
+			}
+			share := *member.Share
+			if split == entities.SplitPercent {
+				share = amount * share / 100
+			}
+			share = min(share, left)
+			shares[member.UserID] = share
+			left -= share
+		}
+	}
+
+	equal := []uuid.UUID{owner}
+	for _, member := range members {
+		if member.Split == entities.SplitEqual {
+			equal = append(equal, member.UserID)
+		}
+	}
+	for _, id := range equal {
+		shares[id] += left / float64(len(equal))
+	}
+
+	return shares[userID]
+}
+
 func parseMonthYear(monthYear string) (time.Time, error) {
 	return time.Parse("01-2006", monthYear)
 }
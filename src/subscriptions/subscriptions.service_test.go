@@ -0,0 +1,520 @@
+package subscriptions
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"effective_mobile/src/_core/metrics"
+	"effective_mobile/src/_core/tenant"
+	entities "effective_mobile/src/_entities"
+	"effective_mobile/src/audit"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var (
+	owner  = uuid.MustParse("00000000-0000-4000-8000-000000000001")
+	alice  = uuid.MustParse("00000000-0000-4000-8000-000000000002")
+	bob    = uuid.MustParse("00000000-0000-4000-8000-000000000003")
+	carol  = uuid.MustParse("00000000-0000-4000-8000-000000000004")
+	nobody = uuid.MustParse("00000000-0000-4000-8000-000000000005")
+)
+
+func month(t *testing.T, monthYear string) time.Time {
+	t.Helper()
+	m, err := parseMonthYear(monthYear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// priced returns an active subscription owned by owner that starts in
+// 01-2026 at price.
+func priced(t *testing.T, price float64) PricedSubscription {
+	return PricedSubscription{Subscriptions: entities.Subscriptions{
+		UserID:    owner,
+		Price:     price,
+		StartDate: month(t, "01-2026"),
+		Status:    entities.StatusActive,
+	}}
+}
+
+func prices(t *testing.T, entries map[string]float64) []entities.SubscriptionPrice {
+	var result []entities.SubscriptionPrice
+	for _, m := range []string{"01-2026", "02-2026", "03-2026", "04-2026", "05-2026", "06-2026"} {
+		if price, ok := entries[m]; ok {
+			result = append(result, entities.SubscriptionPrice{Price: price, EffectiveFrom: month(t, m)})
+		}
+	}
+	return result
+}
+
+func statuses(t *testing.T, entries ...string) []entities.SubscriptionStatus {
+	var result []entities.SubscriptionStatus
+	for i := 0; i < len(entries); i += 2 {
+		result = append(result, entities.SubscriptionStatus{EffectiveFrom: month(t, entries[i]), Status: entries[i+1]})
+	}
+	return result
+}
+
+func TestPriceAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		sub   func(t *testing.T) PricedSubscription
+		month string
+		want  float64
+	}{
+		{
+			name:  "without entries the current price applies",
+			sub:   func(t *testing.T) PricedSubscription { return priced(t, 100) },
+			month: "03-2026",
+			want:  100,
+		},
+		{
+			name: "months before the first entry use it",
+			sub: func(t *testing.T) PricedSubscription {
+				sub := priced(t, 150)
+				sub.Prices = prices(t, map[string]float64{"02-2026": 100, "04-2026": 150})
+				return sub
+			},
+			month: "01-2026",
+			want:  100,
+		},
+		{
+			name: "the last entry effective by the month applies",
+			sub: func(t *testing.T) PricedSubscription {
+				sub := priced(t, 150)
+				sub.Prices = prices(t, map[string]float64{"01-2026": 100, "04-2026": 150})
+				return sub
+			},
+			month: "03-2026",
+			want:  100,
+		},
+		{
+			name: "an entry applies from its own month",
+			sub: func(t *testing.T) PricedSubscription {
+				sub := priced(t, 150)
+				sub.Prices = prices(t, map[string]float64{"01-2026": 100, "04-2026": 150})
+				return sub
+			},
+			month: "04-2026",
+			want:  150,
+		},
+		{
+			name: "a pending change overrides the entries from its month",
+			sub: func(t *testing.T) PricedSubscription {
+				sub := priced(t, 100)
+				sub.Prices = prices(t, map[string]float64{"01-2026": 100})
+				sub.Pending = []entities.SubscriptionChange{{EffectiveFrom: month(t, "05-2026"), Price: ptr(200.0)}}
+				return sub
+			},
+			month: "06-2026",
+			want:  200,
+		},
+		{
+			name: "a pending change without a price keeps it",
+			sub: func(t *testing.T) PricedSubscription {
+				sub := priced(t, 100)
+				sub.Pending = []entities.SubscriptionChange{{EffectiveFrom: month(t, "02-2026"), ServiceName: ptr("Renamed")}}
+				return sub
+			},
+			month: "03-2026",
+			want:  100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sub(t).PriceAt(month(t, tt.month)); !near(got, tt.want) {
+				t.Errorf("PriceAt(%s) = %v, want %v", tt.month, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusAt(t *testing.T) {
+	sub := priced(t, 100)
+	sub.Status = entities.StatusPaused
+	sub.Statuses = statuses(t,
+		"02-2026", entities.StatusTrial,
+		"04-2026", entities.StatusActive,
+		"06-2026", entities.StatusPaused,
+	)
+
+	tests := []struct {
+		month string
+		want  string
+	}{
+		{"01-2026", entities.StatusTrial},
+		{"03-2026", entities.StatusTrial},
+		{"04-2026", entities.StatusActive},
+		{"05-2026", entities.StatusActive},
+		{"09-2026", entities.StatusPaused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.month, func(t *testing.T) {
+			if got := sub.StatusAt(month(t, tt.month)); got != tt.want {
+				t.Errorf("StatusAt(%s) = %q, want %q", tt.month, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("without entries the current status applies", func(t *testing.T) {
+		if got := priced(t, 100).StatusAt(month(t, "03-2026")); got != entities.StatusActive {
+			t.Errorf("StatusAt = %q, want %q", got, entities.StatusActive)
+		}
+	})
+}
+
+func TestPeriodCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		sub        func(t *testing.T) PricedSubscription
+		start, end string
+		user       *uuid.UUID
+		want       cost
+	}{
+		{
+			name: "effective-dated prices",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 150)
+				s.Prices = prices(t, map[string]float64{"01-2026": 100, "04-2026": 150})
+				return s
+			},
+			start: "01-2026", end: "06-2026",
+			want: cost{gross: 750, paid: 750},
+		},
+		{
+			name: "the end date caps the period",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.EndDate = ptr(month(t, "02-2026"))
+				return s
+			},
+			start: "01-2026", end: "06-2026",
+			want: cost{gross: 200, paid: 200},
+		},
+		{
+			name: "trial months are billed at the trial price",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.TrialPrice = ptr(10.0)
+				s.Statuses = statuses(t, "01-2026", entities.StatusTrial, "03-2026", entities.StatusActive)
+				return s
+			},
+			start: "01-2026", end: "04-2026",
+			want: cost{gross: 220, paid: 220},
+		},
+		{
+			name: "trials without a price are free",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.Statuses = statuses(t, "01-2026", entities.StatusTrial, "03-2026", entities.StatusActive)
+				return s
+			},
+			start: "01-2026", end: "04-2026",
+			want: cost{gross: 200, paid: 200},
+		},
+		{
+			name: "paused and cancelled months are free",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.Statuses = statuses(t,
+					"01-2026", entities.StatusActive,
+					"02-2026", entities.StatusPaused,
+					"03-2026", entities.StatusActive,
+					"04-2026", entities.StatusCancelled,
+				)
+				return s
+			},
+			start: "01-2026", end: "06-2026",
+			want: cost{gross: 200, paid: 200},
+		},
+		{
+			name: "discount cycles are counted from before the period",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.Discounts = []entities.SubscriptionDiscount{
+					{Kind: entities.DiscountPercent, Amount: 50, StartDate: month(t, "01-2026"), Cycles: ptr(2)},
+				}
+				return s
+			},
+			start: "02-2026", end: "04-2026",
+			want: cost{gross: 300, discount: 50, paid: 250},
+		},
+		{
+			name: "discount cycles skip months that are not active",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.Statuses = statuses(t,
+					"01-2026", entities.StatusActive,
+					"02-2026", entities.StatusPaused,
+					"03-2026", entities.StatusActive,
+				)
+				s.Discounts = []entities.SubscriptionDiscount{
+					{Kind: entities.DiscountFixed, Amount: 10, StartDate: month(t, "01-2026"), Cycles: ptr(2)},
+				}
+				return s
+			},
+			start: "01-2026", end: "04-2026",
+			want: cost{gross: 300, discount: 20, paid: 280},
+		},
+		{
+			name: "discounts apply within their window",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.Discounts = []entities.SubscriptionDiscount{
+					{Kind: entities.DiscountFixed, Amount: 25, StartDate: month(t, "02-2026"), EndDate: ptr(month(t, "03-2026"))},
+				}
+				return s
+			},
+			start: "01-2026", end: "04-2026",
+			want: cost{gross: 400, discount: 50, paid: 350},
+		},
+		{
+			name: "discounts add up but never exceed the price",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.Discounts = []entities.SubscriptionDiscount{
+					{Kind: entities.DiscountPercent, Amount: 60, StartDate: month(t, "01-2026")},
+					{Kind: entities.DiscountFixed, Amount: 50, StartDate: month(t, "01-2026")},
+				}
+				return s
+			},
+			start: "01-2026", end: "01-2026",
+			want: cost{gross: 100, discount: 100, paid: 0},
+		},
+		{
+			name: "discounts do not apply to trial months",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.TrialPrice = ptr(10.0)
+				s.Statuses = statuses(t, "01-2026", entities.StatusTrial, "02-2026", entities.StatusActive)
+				s.Discounts = []entities.SubscriptionDiscount{
+					{Kind: entities.DiscountFixed, Amount: 5, StartDate: month(t, "01-2026")},
+				}
+				return s
+			},
+			start: "01-2026", end: "02-2026",
+			want: cost{gross: 110, discount: 5, paid: 105},
+		},
+		{
+			name: "an equal member is attributed their share",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 90)
+				s.Members = []entities.SubscriptionMember{{UserID: alice, Split: entities.SplitEqual}, {UserID: bob, Split: entities.SplitEqual}}
+				return s
+			},
+			start: "01-2026", end: "02-2026", user: &alice,
+			want: cost{gross: 60},
+		},
+		{
+			name: "the owner pays the whole net cost",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 90)
+				s.Members = []entities.SubscriptionMember{{UserID: alice, Split: entities.SplitEqual}, {UserID: bob, Split: entities.SplitEqual}}
+				return s
+			},
+			start: "01-2026", end: "02-2026", user: &owner,
+			want: cost{gross: 60, paid: 180},
+		},
+		{
+			name: "shares are computed on the net cost",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.Members = []entities.SubscriptionMember{{UserID: alice, Split: entities.SplitPercent, Share: ptr(40.0)}}
+				s.Discounts = []entities.SubscriptionDiscount{
+					{Kind: entities.DiscountPercent, Amount: 50, StartDate: month(t, "01-2026")},
+				}
+				return s
+			},
+			start: "01-2026", end: "01-2026", user: &alice,
+			want: cost{gross: 40, discount: 20},
+		},
+		{
+			name: "fully discounted months are shared on the price",
+			sub: func(t *testing.T) PricedSubscription {
+				s := priced(t, 100)
+				s.Members = []entities.SubscriptionMember{{UserID: alice, Split: entities.SplitEqual}}
+				s.Discounts = []entities.SubscriptionDiscount{
+					{Kind: entities.DiscountPercent, Amount: 100, StartDate: month(t, "01-2026")},
+				}
+				return s
+			},
+			start: "01-2026", end: "01-2026", user: &alice,
+			want: cost{gross: 50, discount: 50},
+		},
+		{
+			name:  "users who do not share the subscription are attributed nothing",
+			sub:   func(t *testing.T) PricedSubscription { return priced(t, 100) },
+			start: "01-2026", end: "03-2026", user: &nobody,
+			want: cost{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := periodCost(tt.sub(t), month(t, tt.start), month(t, tt.end), tt.user)
+			if !near(got.gross, tt.want.gross) || !near(got.discount, tt.want.discount) || !near(got.paid, tt.want.paid) {
+				t.Errorf("periodCost = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemberShare(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []entities.SubscriptionMember
+		user    uuid.UUID
+		amount  float64
+		want    float64
+	}{
+		{
+			name:   "the owner alone pays everything",
+			user:   owner,
+			amount: 100,
+			want:   100,
+		},
+		{
+			name:    "equal members split with the owner",
+			members: []entities.SubscriptionMember{{UserID: alice, Split: entities.SplitEqual}, {UserID: bob, Split: entities.SplitEqual}},
+			user:    bob,
+			amount:  90,
+			want:    30,
+		},
+		{
+			name:    "percent members take their percentage",
+			members: []entities.SubscriptionMember{{UserID: alice, Split: entities.SplitPercent, Share: ptr(25.0)}},
+			user:    alice,
+			amount:  80,
+			want:    20,
+		},
+		{
+			name:    "the owner pays what percent members leave",
+			members: []entities.SubscriptionMember{{UserID: alice, Split: entities.SplitPercent, Share: ptr(25.0)}},
+			user:    owner,
+			amount:  80,
+			want:    60,
+		},
+		{
+			name:    "fixed members take their amount",
+			members: []entities.SubscriptionMember{{UserID: alice, Split: entities.SplitFixed, Share: ptr(15.0)}},
+			user:    alice,
+			amount:  100,
+			want:    15,
+		},
+		{
+			name: "fixed shares are capped at what percent members leave",
+			members: []entities.SubscriptionMember{
+				{UserID: alice, Split: entities.SplitFixed, Share: ptr(50.0)},
+				{UserID: bob, Split: entities.SplitPercent, Share: ptr(70.0)},
+			},
+			user:   alice,
+			amount: 100,
+			want:   30,
+		},
+		{
+			name: "later fixed members get nothing once the amount is used up",
+			members: []entities.SubscriptionMember{
+				{UserID: alice, Split: entities.SplitFixed, Share: ptr(80.0)},
+				{UserID: bob, Split: entities.SplitFixed, Share: ptr(80.0)},
+			},
+			user:   bob,
+			amount: 100,
+			want:   20,
+		},
+		{
+			name: "equal members split the remainder",
+			members: []entities.SubscriptionMember{
+				{UserID: alice, Split: entities.SplitFixed, Share: ptr(40.0)},
+				{UserID: bob, Split: entities.SplitEqual},
+				{UserID: carol, Split: entities.SplitEqual},
+			},
+			user:   carol,
+			amount: 100,
+			want:   20,
+		},
+		{
+			name: "nothing is left for equal members once shares use it up",
+			members: []entities.SubscriptionMember{
+				{UserID: alice, Split: entities.SplitPercent, Share: ptr(100.0)},
+				{UserID: bob, Split: entities.SplitEqual},
+			},
+			user:   bob,
+			amount: 100,
+			want:   0,
+		},
+		{
+			name:    "non-members get nothing",
+			members: []entities.SubscriptionMember{{UserID: alice, Split: entities.SplitEqual}},
+			user:    nobody,
+			amount:  100,
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := memberShare(tt.members, owner, tt.user, tt.amount); !near(got, tt.want) {
+				t.Errorf("memberShare = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshMetrics(t *testing.T) {
+	service := NewSubscriptionService(NewMemorySubscriptionRepo(), audit.NewAuditService(audit.NewMemoryAuditRepo()))
+	now := formatMonthYear(time.Now().UTC())
+	later := formatMonthYear(time.Now().UTC().AddDate(1, 0, 0))
+
+	subs := []struct {
+		tenant uuid.UUID
+		data   CreateSubscription
+	}{
+		{tenantA, CreateSubscription{ServiceName: "Netflix", Price: 100, UserID: alice.String(), StartDate: now, EndDate: later}},
+		{tenantA, CreateSubscription{ServiceName: "Spotify", Price: 200, UserID: alice.String(), StartDate: now, EndDate: later,
+			Status: entities.StatusTrial, TrialEnd: later, TrialPrice: ptr(20.0)}},
+		{tenantB, CreateSubscription{ServiceName: "Okko", Price: 300, UserID: bob.String(), StartDate: now, EndDate: later,
+			Status: entities.StatusTrial, TrialEnd: later, TrialPrice: ptr(50.0)}},
+	}
+	for _, sub := range subs {
+		if _, err := service.Create(tenant.WithID(context.Background(), sub.tenant), sub.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := service.RefreshMetrics(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tenant uuid.UUID
+		active float64
+		spend  float64
+	}{
+		{tenantA, 1, 120},
+		{tenantB, 0, 50},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(metrics.ActiveSubscriptions.WithLabelValues(tt.tenant.String())); got != tt.active {
+			t.Errorf("active subscriptions of %v = %v, want %v", tt.tenant, got, tt.active)
+		}
+		if got := testutil.ToFloat64(metrics.MonthlyRecurringSpend.WithLabelValues(tt.tenant.String())); !near(got, tt.spend) {
+			t.Errorf("monthly spend of %v = %v, want %v", tt.tenant, got, tt.spend)
+		}
+	}
+}